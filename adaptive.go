@@ -0,0 +1,115 @@
+// SPDX-License-Identifier: MIT
+//
+// Copyright 2022 Andrew Bursavich. All rights reserved.
+// Use of this source code is governed by The MIT License
+// which can be found in the LICENSE file.
+
+package retry
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	// minAdaptiveJitter and maxAdaptiveJitter bound the factor of adaptive jitter.
+	minAdaptiveJitter = 0.05
+	maxAdaptiveJitter = 1
+
+	// The factor of adaptive jitter is widened on each collision and narrowed otherwise.
+	// The steps are chosen so that it settles where about one in ten wakes collides.
+	adaptiveWiden  = 1.1
+	adaptiveNarrow = 0.99
+)
+
+// WithAdaptiveJitter returns a Policy that wraps the parent Policy and adds or subtracts
+// random jitter as a factor of its backoff, like WithRandomJitter, except that the factor
+// tunes itself to desynchronize the loops that share the Policy.
+//
+// It observes the times at which the loops are scheduled to wake. When a loop is scheduled
+// to wake less than the window before or after another loop's pending wake, it's counted
+// as a collision and the jitter is widened; otherwise it's narrowed. If the window isn't
+// positive, only wakes that are scheduled for the same instant collide. The factor starts at the default of
+// 50% and stays within [5%, 100%].
+//
+// The Policy should be shared by all of the loops that should be desynchronized,
+// such as those of a client's connections to a single backend.
+func WithAdaptiveJitter(parent Policy, window time.Duration) Policy {
+	return &adaptiveJitter{
+		parent: parent,
+		window: window,
+		factor: DefaultJitterFactor,
+	}
+}
+
+type adaptiveJitter struct {
+	parent Policy
+	window time.Duration
+
+	mu     sync.Mutex
+	factor float64
+	wakes  []time.Time // pending wakes in order
+}
+
+func (p *adaptiveJitter) Next(err error, start, now time.Time, attempt int) (time.Duration, bool) {
+	return p.NextState(&State{Err: err, Start: start, Now: now, Attempt: attempt})
+}
+
+func (p *adaptiveJitter) NextState(s *State) (time.Duration, bool) {
+	d, allow := next(p.parent, s)
+	if !allow {
+		return 0, false
+	}
+	if s.exact {
+		return d, true
+	}
+	r := s.float64()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	d = time.Duration(float64(d) * (1 + (p.factor * (2*r - 1))))
+	p.schedule(s.Now, s.Now.Add(d))
+	return d, true
+}
+
+// schedule records the wake and adjusts the factor depending on whether it collides.
+func (p *adaptiveJitter) schedule(now, wake time.Time) {
+	// Forget the wakes that have passed.
+	n := 0
+	for n < len(p.wakes) && !p.wakes[n].After(now) {
+		n++
+	}
+	p.wakes = slices.Delete(p.wakes, 0, n)
+
+	// Only the closest pending wakes before and after it may collide.
+	i, _ := slices.BinarySearchFunc(p.wakes, wake, time.Time.Compare)
+	if (i > 0 && p.collides(p.wakes[i-1], wake)) || (i < len(p.wakes) && p.collides(wake, p.wakes[i])) {
+		p.factor = min(p.factor*adaptiveWiden, maxAdaptiveJitter)
+	} else {
+		p.factor = max(p.factor*adaptiveNarrow, minAdaptiveJitter)
+	}
+	p.wakes = slices.Insert(p.wakes, i, wake)
+}
+
+// collides reports whether the wakes, where a isn't after b, are within the window.
+func (p *adaptiveJitter) collides(a, b time.Time) bool {
+	return b.Sub(a) < max(p.window, 1)
+}
+
+func (p *adaptiveJitter) String() string {
+	return fmt.Sprintf("WithAdaptiveJitter(%v)", p.window)
+}
+
+func (p *adaptiveJitter) parents() []Policy { return []Policy{p.parent} }
+
+func (p *adaptiveJitter) jitter() {}
+
+func (p *adaptiveJitter) bounds(attempt int, elapsed time.Duration) (time.Duration, time.Duration, bool) {
+	min, max, ok := bounds(p.parent, attempt, elapsed)
+	if !ok {
+		return 0, 0, false
+	}
+	return time.Duration(float64(min) * (1 - maxAdaptiveJitter)), time.Duration(float64(max) * (1 + maxAdaptiveJitter)), true
+}
@@ -0,0 +1,123 @@
+// SPDX-License-Identifier: MIT
+//
+// Copyright 2022 Andrew Bursavich. All rights reserved.
+// Use of this source code is governed by The MIT License
+// which can be found in the LICENSE file.
+
+package retry
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAdaptiveJitterCollisions(t *testing.T) {
+	start := time.Unix(1000, 0) // aligned to the window
+	at := func(d time.Duration) time.Time { return start.Add(d) }
+	tests := []struct {
+		name   string
+		window time.Duration
+		wakes  [][2]time.Duration // of now and the scheduled wake
+		widen  bool               // whether the last wake widens the jitter
+	}{
+		{
+			name:   "neighbors across the window boundary",
+			window: time.Second,
+			wakes:  [][2]time.Duration{{0, 999 * time.Millisecond}, {0, 1001 * time.Millisecond}},
+			widen:  true,
+		},
+		{
+			name:   "neighbor after",
+			window: time.Second,
+			wakes:  [][2]time.Duration{{0, 3 * time.Second}, {0, 2500 * time.Millisecond}},
+			widen:  true,
+		},
+		{
+			name:   "apart",
+			window: time.Second,
+			wakes:  [][2]time.Duration{{0, 100 * time.Millisecond}, {0, 1100 * time.Millisecond}},
+		},
+		{
+			name:   "between apart neighbors",
+			window: time.Second,
+			wakes:  [][2]time.Duration{{0, time.Second}, {0, 5 * time.Second}, {0, 3 * time.Second}},
+		},
+		{
+			name:   "passed",
+			window: time.Second,
+			wakes:  [][2]time.Duration{{0, time.Second}, {2 * time.Second, 2500 * time.Millisecond}},
+		},
+		{
+			name:  "same instant without a window",
+			wakes: [][2]time.Duration{{0, time.Second}, {0, time.Second}},
+			widen: true,
+		},
+		{
+			name:  "different instants without a window",
+			wakes: [][2]time.Duration{{0, time.Second}, {0, time.Second + 1}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := WithAdaptiveJitter(ConstantBackoff(time.Second), tt.window).(*adaptiveJitter)
+			var before float64
+			for _, w := range tt.wakes {
+				before = p.factor
+				p.schedule(at(w[0]), at(w[1]))
+			}
+			if widened := p.factor > before; widened != tt.widen {
+				t.Errorf("factor: got %v after %v; want widened: %v", p.factor, before, tt.widen)
+			}
+		})
+	}
+}
+
+func TestAdaptiveJitterFactorBounds(t *testing.T) {
+	now := time.Unix(1000, 0)
+	p := WithAdaptiveJitter(ConstantBackoff(time.Second), time.Second).(*adaptiveJitter)
+	for range 100 {
+		p.schedule(now, now.Add(time.Second))
+	}
+	if p.factor != maxAdaptiveJitter {
+		t.Errorf("factor after collisions: got %v; want %v", p.factor, maxAdaptiveJitter)
+	}
+	for i := range 1000 {
+		p.schedule(now, now.Add(time.Duration(i+2)*time.Hour))
+	}
+	if p.factor != minAdaptiveJitter {
+		t.Errorf("factor after spreading: got %v; want %v", p.factor, minAdaptiveJitter)
+	}
+}
+
+func TestAdaptiveJitterNextState(t *testing.T) {
+	now := time.Unix(1000, 0)
+	p := WithAdaptiveJitter(WithMaxRetries(ConstantBackoff(time.Second), 1), time.Millisecond).(*adaptiveJitter)
+
+	s := &State{Start: now, Now: now, Attempt: 1}
+	factor := p.factor
+	d, ok := p.NextState(s)
+	if lo, hi := time.Duration(float64(time.Second)*(1-factor)), time.Duration(float64(time.Second)*(1+factor)); !ok || d < lo || d > hi {
+		t.Errorf("backoff: got (%v, %v); want (%v to %v, true)", d, ok, lo, hi)
+	}
+	if len(p.wakes) != 1 || !p.wakes[0].Equal(now.Add(d)) {
+		t.Errorf("wakes: got %v; want [%v]", p.wakes, now.Add(d))
+	}
+
+	s = &State{Start: now, Now: now, Attempt: 1, exact: true}
+	if d, ok := p.NextState(s); !ok || d != time.Second {
+		t.Errorf("exact backoff: got (%v, %v); want (1s, true)", d, ok)
+	}
+
+	s = &State{Start: now, Now: now, Attempt: 2}
+	if _, ok := p.NextState(s); ok {
+		t.Error("stopped parent: got true; want false")
+	}
+
+	lo, hi, ok := p.bounds(1, 0)
+	if want := 2 * time.Second; !ok || lo != 0 || hi != want {
+		t.Errorf("bounds: got (%v, %v, %v); want (0s, %v, true)", lo, hi, ok, want)
+	}
+	if want := "WithAdaptiveJitter(1ms)"; p.String() != want {
+		t.Errorf("String: got %q; want %q", p.String(), want)
+	}
+}
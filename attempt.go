@@ -0,0 +1,99 @@
+// SPDX-License-Identifier: MIT
+//
+// Copyright 2022 Andrew Bursavich. All rights reserved.
+// Use of this source code is governed by The MIT License
+// which can be found in the LICENSE file.
+
+package retry
+
+import (
+	"context"
+	"time"
+)
+
+// Attempt describes an attempt made by a retry loop.
+//
+// Its times are read from the Retrier's Clock. Times from the system clock carry
+// monotonic clock readings, so the durations between them, such as the time spent
+// in the function, sleeping, or delayed by the scheduler, can be computed precisely.
+type Attempt struct {
+	// Number is the number of the attempt, starting at 1.
+	Number int
+	// Start is the time at which the attempt started.
+	Start time.Time
+	// End is the time at which the attempt returned, or zero if it hasn't returned yet.
+	End time.Time
+	// BackoffStart is the time at which the backoff after the attempt started,
+	// or zero if it hasn't started yet.
+	BackoffStart time.Time
+	// Woke is the time at which the backoff before the attempt ended, or zero for
+	// the first attempt. The difference between Woke and the end of LastBackoff
+	// is the delay caused by the scheduler.
+	Woke time.Time
+	// LoopStart is the time at which the retry loop started. It precedes the first
+	// attempt by the initial delay, if one is configured by WithInitialDelay.
+	LoopStart time.Time
+	// Loop identifies the retry loop. It's unique among the retry loops of the process,
+	// so it distinguishes loops that started at the same time.
+	Loop uint64
+	// PrevErr is the error returned by the previous attempt, or nil for the first attempt.
+	PrevErr error
+	// LastBackoff is the backoff waited before the attempt, or zero for the first attempt.
+	LastBackoff time.Duration
+	// Remaining is an estimate of the number of attempts that remain after the attempt,
+	// if it fails. It's -1 if it isn't estimated, because the Retrier wasn't configured
+	// with WithRemainingEstimate, or if the policy retries indefinitely.
+	Remaining int
+}
+
+// Latest returns the latest of the attempt's times, which are read from the Retrier's Clock.
+// It's the time of the most recent event of the attempt, such as for measuring the elapsed
+// duration of the retry loop when it gives up.
+func (a Attempt) Latest() time.Time {
+	t := a.LoopStart
+	for _, u := range []time.Time{a.Start, a.End, a.BackoffStart, a.Woke} {
+		if u.After(t) {
+			t = u
+		}
+	}
+	return t
+}
+
+type attemptKey struct{}
+
+// AttemptFromContext returns the Attempt of the retry loop with which ctx is associated,
+// such as for logging it or setting an "x-attempt" request header. Contexts passed to
+// the function by DoCtx are associated with their attempt.
+func AttemptFromContext(ctx context.Context) (Attempt, bool) {
+	a, ok := ctx.Value(attemptKey{}).(Attempt)
+	return a, ok
+}
+
+// estimateRemaining estimates the number of attempts that remain after the attempt.
+// Backoffs are assumed to be the midpoint of their bounds and attempts are assumed to take
+// the average duration of the previous attempts.
+func (r *Retrier) estimateRemaining(a Attempt, slept time.Duration, deadline time.Time, hasDeadline bool, tight tightening) int {
+	var took time.Duration
+	elapsed := a.Start.Sub(a.LoopStart)
+	if a.Number > 1 {
+		took = (elapsed - slept) / time.Duration(a.Number-1)
+	}
+	elapsed += took
+	n := 0
+	for ; n < maxAmplificationAttempts; n++ {
+		if !tight.allows(a.Number + n) {
+			return n
+		}
+		virtual := r.virtual(a.LoopStart, a.LoopStart.Add(elapsed)).Sub(a.LoopStart)
+		min, max, ok := bounds(r.policy, a.Number+n, virtual)
+		if !ok {
+			return n
+		}
+		elapsed += tight.cap(r.real(min + (max-min)/2))
+		if hasDeadline && deadline.Before(a.LoopStart.Add(elapsed)) {
+			return n
+		}
+		elapsed += took
+	}
+	return -1
+}
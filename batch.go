@@ -0,0 +1,97 @@
+// SPDX-License-Identifier: MIT
+//
+// Copyright 2022 Andrew Bursavich. All rights reserved.
+// Use of this source code is governed by The MIT License
+// which can be found in the LICENSE file.
+
+package retry
+
+import (
+	"context"
+	"errors"
+)
+
+// A BatchOption configures a BatchFlusher.
+type BatchOption[T any] interface {
+	apply(*BatchFlusher[T])
+}
+
+type batchOptionFunc[T any] func(*BatchFlusher[T])
+
+func (fn batchOptionFunc[T]) apply(b *BatchFlusher[T]) { fn(b) }
+
+// WithPoisonHandler returns a BatchOption that routes each poison item, which individually
+// exhausted its retries, to handle instead of returning it from Flush. For example, it may
+// write the item to a dead-letter queue or quarantine it for inspection.
+func WithPoisonHandler[T any](handle func(ctx context.Context, item T, err error)) BatchOption[T] {
+	return batchOptionFunc[T](func(b *BatchFlusher[T]) {
+		b.poison = handle
+	})
+}
+
+// WithBatchRetrierOptions returns a BatchOption that configures the Retrier
+// that flushes each batch with the given options.
+func WithBatchRetrierOptions[T any](opts ...Option) BatchOption[T] {
+	return batchOptionFunc[T](func(b *BatchFlusher[T]) {
+		b.opts = append(b.opts, opts...)
+	})
+}
+
+// BatchFlusher flushes batches of items, such as metrics, logs, or events, according to a Policy.
+//
+// If a batch can't be flushed, it's split in half and each half is flushed separately.
+// Splitting continues until the poison items that can never be flushed are isolated,
+// so that they don't prevent the rest of the batch from being flushed.
+type BatchFlusher[T any] struct {
+	r      *Retrier
+	opts   []Option
+	flush  func(context.Context, []T) error
+	poison func(context.Context, T, error)
+}
+
+// NewBatchFlusher returns a new BatchFlusher that calls flush according to the given policy.
+func NewBatchFlusher[T any](policy Policy, flush func(ctx context.Context, batch []T) error, opts ...BatchOption[T]) *BatchFlusher[T] {
+	b := &BatchFlusher[T]{flush: flush}
+	for _, o := range opts {
+		o.apply(b)
+	}
+	b.r = New(policy, b.opts...)
+	return b
+}
+
+// Flush flushes the items and returns the items that couldn't be flushed
+// along with their errors joined together.
+//
+// Each batch and sub-batch is retried according to the policy before it's split,
+// unless it fails with a permanent error. If ctx is done, the items that
+// haven't been flushed are returned.
+//
+// If the BatchFlusher has a poison handler, poison items are routed to it
+// instead of being returned.
+func (b *BatchFlusher[T]) Flush(ctx context.Context, items []T) (failed []T, err error) {
+	var errs []error
+	b.flushBatch(ctx, items, &failed, &errs)
+	return failed, errors.Join(errs...)
+}
+
+func (b *BatchFlusher[T]) flushBatch(ctx context.Context, batch []T, failed *[]T, errs *[]error) {
+	if len(batch) == 0 {
+		return
+	}
+	err := b.r.Do(ctx, func() error { return b.flush(ctx, batch) })
+	if err == nil {
+		return
+	}
+	if len(batch) == 1 && b.poison != nil && ctx.Err() == nil {
+		b.poison(ctx, batch[0], err)
+		return
+	}
+	if len(batch) == 1 || ctx.Err() != nil {
+		*failed = append(*failed, batch...)
+		*errs = append(*errs, err)
+		return
+	}
+	mid := len(batch) / 2
+	b.flushBatch(ctx, batch[:mid], failed, errs)
+	b.flushBatch(ctx, batch[mid:], failed, errs)
+}
@@ -0,0 +1,132 @@
+// SPDX-License-Identifier: MIT
+//
+// Copyright 2022 Andrew Bursavich. All rights reserved.
+// Use of this source code is governed by The MIT License
+// which can be found in the LICENSE file.
+
+package retry_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"bursavich.dev/retry"
+	"bursavich.dev/retry/retrytest"
+)
+
+func TestBatchFlusher(t *testing.T) {
+	poison := map[int]bool{3: true, 6: true}
+	tests := []struct {
+		name     string
+		poison   bool // whether to use a poison handler
+		failed   []int
+		poisoned []int
+	}{
+		{
+			name:   "returned",
+			failed: []int{3, 6},
+		},
+		{
+			name:     "poison handler",
+			poison:   true,
+			poisoned: []int{3, 6},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				flushed  []int
+				poisoned []int
+				attempts int
+			)
+			opts := []retry.BatchOption[int]{
+				retry.WithBatchRetrierOptions[int](retry.WithClock(retrytest.NewAutoClock(time.Now()))),
+			}
+			if tt.poison {
+				opts = append(opts, retry.WithPoisonHandler(func(ctx context.Context, item int, err error) {
+					poisoned = append(poisoned, item)
+				}))
+			}
+			b := retry.NewBatchFlusher(
+				retry.WithMaxRetries(retry.ConstantBackoff(time.Second), 1),
+				func(ctx context.Context, batch []int) error {
+					attempts++
+					for _, item := range batch {
+						if poison[item] {
+							return errTest
+						}
+					}
+					flushed = append(flushed, batch...)
+					return nil
+				},
+				opts...,
+			)
+
+			failed, err := b.Flush(context.Background(), []int{1, 2, 3, 4, 5, 6, 7, 8})
+			if !slices.Equal(failed, tt.failed) {
+				t.Errorf("failed: got %v; want %v", failed, tt.failed)
+			}
+			if (err != nil) != (len(tt.failed) > 0) || (err != nil && !errors.Is(err, errTest)) {
+				t.Errorf("error: got %v; want errors for %v", err, tt.failed)
+			}
+			if !slices.Equal(poisoned, tt.poisoned) {
+				t.Errorf("poisoned: got %v; want %v", poisoned, tt.poisoned)
+			}
+			// The poison items are isolated by bisecting the failed batches.
+			if want := []int{1, 2, 4, 5, 7, 8}; !slices.Equal(flushed, want) {
+				t.Errorf("flushed: got %v; want %v", flushed, want)
+			}
+			// The failed batches, [1-8], [1-4], [3 4], [3], [5-8], [5 6], and [6], are retried
+			// once, while [1 2], [4], [5], and [7 8] are flushed on their first attempt.
+			if want := 2*7 + 4; attempts != want {
+				t.Errorf("attempts: got %d; want %d", attempts, want)
+			}
+		})
+	}
+}
+
+func TestBatchFlusherPermanentError(t *testing.T) {
+	attempts := 0
+	b := retry.NewBatchFlusher(
+		retry.ConstantBackoff(time.Second),
+		func(ctx context.Context, batch []int) error {
+			attempts++
+			if slices.Contains(batch, 2) {
+				return retry.NewPermanentError(errTest)
+			}
+			return nil
+		},
+		retry.WithBatchRetrierOptions[int](retry.WithClock(retrytest.NewAutoClock(time.Now()))),
+	)
+	failed, err := b.Flush(context.Background(), []int{1, 2})
+	if want := []int{2}; !slices.Equal(failed, want) {
+		t.Errorf("failed: got %v; want %v", failed, want)
+	}
+	if !errors.Is(err, errTest) {
+		t.Errorf("error: got %v; want %v", err, errTest)
+	}
+	// Permanent errors aren't retried before the batch is split: [1 2], [1], [2].
+	if attempts != 3 {
+		t.Errorf("attempts: got %d; want 3", attempts)
+	}
+}
+
+func TestBatchFlusherContextDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	b := retry.NewBatchFlusher(
+		retry.ConstantBackoff(time.Second),
+		func(ctx context.Context, batch []int) error { return errTest },
+	)
+	items := []int{1, 2, 3, 4}
+	failed, err := b.Flush(ctx, items)
+	if !slices.Equal(failed, items) {
+		t.Errorf("failed: got %v; want %v", failed, items)
+	}
+	if err == nil {
+		t.Error("error: got nil; want non-nil")
+	}
+}
@@ -0,0 +1,79 @@
+// SPDX-License-Identifier: MIT
+//
+// Copyright 2022 Andrew Bursavich. All rights reserved.
+// Use of this source code is governed by The MIT License
+// which can be found in the LICENSE file.
+
+package retry
+
+import "context"
+
+// DoValueCached executes the retriable function according to the given policy and Retrier
+// options and returns the results, like DoValue, except that lookup is consulted after
+// each failed attempt that isn't permanent. If lookup returns a stale-but-valid cached
+// value, it's returned immediately instead of waiting for the next attempt, and the retry
+// loop stops.
+func DoValueCached[T any](ctx context.Context, policy Policy, fn func() (T, error), lookup func(ctx context.Context) (T, bool), opts ...Option) (T, error) {
+	var v, cached T
+	hit := false
+	err := New(policy, opts...).run(ctx, loop{
+		fn: func(context.Context) error {
+			var err error
+			v, err = fn()
+			return err
+		},
+		interrupt: func(ctx context.Context, err error) bool {
+			cached, hit = lookup(ctx)
+			return hit
+		},
+	})
+	if hit {
+		return cached, nil
+	}
+	return v, err
+}
+
+// DoValueCachedRefresh is like DoValueCached, except that if a cached value is returned,
+// the same retry loop continues in the background, backing off before its next attempt
+// as usual, and a Handle for monitoring it is returned. Lookup isn't consulted again.
+// The background loop isn't canceled when ctx is canceled, so it must be canceled with
+// the Handle if it shouldn't run until it finishes. The values produced in the background
+// are discarded, so fn should populate the cache itself. Otherwise, the returned Handle
+// is nil.
+//
+// The retry loop doesn't observe ctx's deadline. If ctx is done before a cached value
+// is found, the retry loop is canceled and its error is returned.
+func DoValueCachedRefresh[T any](ctx context.Context, policy Policy, fn func() (T, error), lookup func(ctx context.Context) (T, bool), opts ...Option) (T, *Handle, error) {
+	var v, cached T
+	hit := make(chan struct{})
+	found := false
+	h := goRun(context.WithoutCancel(ctx), New(policy, opts...), func() error {
+		var err error
+		v, err = fn()
+		return err
+	}, loop{
+		interrupt: func(_ context.Context, err error) bool {
+			if !found {
+				if cached, found = lookup(ctx); found {
+					close(hit)
+				}
+			}
+			return false // Continue in the background.
+		},
+	})
+	select {
+	case <-hit:
+		return cached, h, nil
+	case <-h.Done():
+		select {
+		case <-hit:
+			return cached, h, nil
+		default:
+			return v, nil, h.Err()
+		}
+	case <-ctx.Done():
+		h.Cancel()
+		<-h.Done()
+		return v, nil, h.Err()
+	}
+}
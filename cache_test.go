@@ -0,0 +1,159 @@
+// SPDX-License-Identifier: MIT
+//
+// Copyright 2022 Andrew Bursavich. All rights reserved.
+// Use of this source code is governed by The MIT License
+// which can be found in the LICENSE file.
+
+package retry_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"bursavich.dev/retry"
+	"bursavich.dev/retry/retrytest"
+)
+
+func TestDoValueCached(t *testing.T) {
+	tests := []struct {
+		name     string
+		errs     []error // returned by the attempts, followed by success
+		found    bool    // whether lookup finds a cached value
+		want     string
+		wantErr  error
+		attempts int
+		lookups  int
+	}{
+		{
+			name:     "success",
+			want:     "fresh",
+			attempts: 1,
+		},
+		{
+			name:     "cached",
+			errs:     []error{errTest, errTest},
+			found:    true,
+			want:     "stale",
+			attempts: 1,
+			lookups:  1,
+		},
+		{
+			name:     "not cached",
+			errs:     []error{errTest, errTest},
+			want:     "fresh",
+			attempts: 3,
+			lookups:  2,
+		},
+		{
+			name:     "permanent",
+			errs:     []error{retry.NewPermanentError(errTest)},
+			found:    true,
+			wantErr:  errTest,
+			attempts: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts, lookups := 0, 0
+			fn := func() (string, error) {
+				if attempts++; attempts <= len(tt.errs) {
+					return "", tt.errs[attempts-1]
+				}
+				return "fresh", nil
+			}
+			lookup := func(context.Context) (string, bool) {
+				lookups++
+				return "stale", tt.found
+			}
+			clock := retrytest.NewAutoClock(time.Now())
+			v, err := retry.DoValueCached(context.Background(), retry.ConstantBackoff(time.Second), fn, lookup, retry.WithClock(clock))
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Errorf("error: got %v; want %v", err, tt.wantErr)
+			}
+			if v != tt.want {
+				t.Errorf("value: got %q; want %q", v, tt.want)
+			}
+			if attempts != tt.attempts {
+				t.Errorf("attempts: got %d; want %d", attempts, tt.attempts)
+			}
+			if lookups != tt.lookups {
+				t.Errorf("lookups: got %d; want %d", lookups, tt.lookups)
+			}
+		})
+	}
+}
+
+func TestDoValueCachedRefreshMiss(t *testing.T) {
+	attempts := 0
+	fn := func() (string, error) {
+		if attempts++; attempts < 3 {
+			return "", errTest
+		}
+		return "fresh", nil
+	}
+	lookup := func(context.Context) (string, bool) { return "", false }
+	clock := retrytest.NewAutoClock(time.Now())
+	v, h, err := retry.DoValueCachedRefresh(context.Background(), retry.ConstantBackoff(time.Second), fn, lookup, retry.WithClock(clock))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if v != "fresh" {
+		t.Errorf("value: got %q; want %q", v, "fresh")
+	}
+	if h != nil {
+		t.Error("handle: got non-nil; want nil without a cached value")
+	}
+}
+
+func TestDoValueCachedRefreshBacksOff(t *testing.T) {
+	const backoff = 50 * time.Millisecond
+	var (
+		mu     sync.Mutex
+		starts []time.Time
+	)
+	fn := func() (string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		starts = append(starts, time.Now())
+		if len(starts) < 3 {
+			return "", errTest
+		}
+		return "fresh", nil
+	}
+	lookups := 0
+	lookup := func(context.Context) (string, bool) {
+		lookups++
+		return "stale", true
+	}
+
+	v, h, err := retry.DoValueCachedRefresh(context.Background(), retry.ConstantBackoff(backoff), fn, lookup)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if v != "stale" {
+		t.Errorf("value: got %q; want %q", v, "stale")
+	}
+	if h == nil {
+		t.Fatal("handle: got nil; want background refresh")
+	}
+	if err := h.Wait(); err != nil {
+		t.Fatalf("background refresh: unexpected error: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if got, want := len(starts), 3; got != want {
+		t.Fatalf("attempts: got %d; want %d", got, want)
+	}
+	for i := 1; i < len(starts); i++ {
+		if d := starts[i].Sub(starts[i-1]); d < backoff {
+			t.Errorf("attempt %d: started %v after the previous attempt; want at least %v", i+1, d, backoff)
+		}
+	}
+	if lookups != 1 {
+		t.Errorf("lookups: got %d; want 1", lookups)
+	}
+}
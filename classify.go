@@ -0,0 +1,208 @@
+// SPDX-License-Identifier: MIT
+//
+// Copyright 2022 Andrew Bursavich. All rights reserved.
+// Use of this source code is governed by The MIT License
+// which can be found in the LICENSE file.
+
+package retry
+
+import (
+	"context"
+	"errors"
+	"strconv"
+)
+
+// Precedence determines how an error is classified if it has multiple classifiable causes,
+// such as a permanent error that wraps a temporary network error.
+//
+// Permanent errors and errors with a Temporary method that returns true are classifiable.
+type Precedence int
+
+const (
+	// PermanentWins classifies an error as permanent if any error in its tree is permanent.
+	// It's the default.
+	PermanentWins Precedence = iota
+	// InnermostWins classifies an error by its innermost classifiable cause.
+	InnermostWins
+)
+
+var precedenceNames = [...]string{
+	PermanentWins: "permanent wins",
+	InnermostWins: "innermost wins",
+}
+
+func (p Precedence) String() string {
+	if p < 0 || int(p) >= len(precedenceNames) {
+		return "Precedence(" + strconv.Itoa(int(p)) + ")"
+	}
+	return precedenceNames[p]
+}
+
+// WithPrecedence returns an Option that sets the precedence used to classify errors
+// that have multiple classifiable causes.
+func WithPrecedence(p Precedence) Option {
+	return optionFunc(func(r *Retrier) {
+		r.precedence = p
+	})
+}
+
+// Precedence returns the precedence used by the Retrier to classify errors.
+func (r *Retrier) Precedence() Precedence {
+	return r.precedence
+}
+
+// JoinMode determines how an error that joins multiple errors, such as with errors.Join,
+// is classified if some of them are permanent and others aren't.
+type JoinMode int
+
+const (
+	// AnyPermanent classifies joined errors as permanent if any of them is permanent.
+	// It's the default.
+	AnyPermanent JoinMode = iota
+	// AllPermanent classifies joined errors as permanent only if all of them are permanent.
+	AllPermanent
+)
+
+var joinModeNames = [...]string{
+	AnyPermanent: "any permanent",
+	AllPermanent: "all permanent",
+}
+
+func (m JoinMode) String() string {
+	if m < 0 || int(m) >= len(joinModeNames) {
+		return "JoinMode(" + strconv.Itoa(int(m)) + ")"
+	}
+	return joinModeNames[m]
+}
+
+// WithJoinMode returns an Option that sets the mode used to classify errors
+// that join multiple errors.
+func WithJoinMode(m JoinMode) Option {
+	return optionFunc(func(r *Retrier) {
+		r.joinMode = m
+	})
+}
+
+// JoinMode returns the mode used by the Retrier to classify joined errors.
+func (r *Retrier) JoinMode() JoinMode {
+	return r.joinMode
+}
+
+// IsPermanent reports whether err is classified as permanent with the given precedence.
+// Joined errors are classified as permanent if any of them is permanent.
+func IsPermanent(err error, p Precedence) bool {
+	return IsPermanentJoin(err, p, AnyPermanent)
+}
+
+// IsPermanentJoin reports whether err is classified as permanent with the given
+// precedence and join mode.
+func IsPermanentJoin(err error, p Precedence, m JoinMode) bool {
+	switch {
+	case p == InnermostWins:
+		return innermost(err, m) == classPermanent
+	case m == AllPermanent:
+		return allPermanent(err)
+	default:
+		return isPermErr(err)
+	}
+}
+
+// ContextDone reports whether err was caused by ctx being done, as opposed to the
+// cancellation of an unrelated context, such as one that's internal to a library that
+// leaks its cancellation errors. Typically ctx is the attempt's context provided by DoCtx.
+//
+// Errors caused by ctx being done shouldn't be retried, but errors such as context.Canceled
+// that were returned while ctx isn't done are often transient. For example:
+//
+//	err := retry.DoCtx(ctx, policy, func(ctx context.Context) error {
+//		err := client.Call(ctx)
+//		if retry.ContextDone(ctx, err) {
+//			return retry.NewPermanentError(err)
+//		}
+//		return err
+//	})
+func ContextDone(ctx context.Context, err error) bool {
+	ctxErr := ctx.Err()
+	if err == nil || ctxErr == nil {
+		return false
+	}
+	if errors.Is(err, ctxErr) {
+		return true
+	}
+	cause := context.Cause(ctx)
+	return cause != ctxErr && errors.Is(err, cause)
+}
+
+type class int
+
+const (
+	classNone class = iota
+	classPermanent
+	classTemporary
+)
+
+// innermost returns the class of the innermost classifiable error in err's tree.
+// If multiple branches of the tree are classifiable, the join mode decides if permanent wins.
+func innermost(err error, m JoinMode) class {
+	switch x := err.(type) {
+	case interface{ Unwrap() error }:
+		if c := innermost(x.Unwrap(), m); c != classNone {
+			return c
+		}
+	case interface{ Unwrap() []error }:
+		errs := x.Unwrap()
+		found, all := classNone, len(errs) > 0
+		for _, err := range errs {
+			switch innermost(err, m) {
+			case classPermanent:
+				if m == AnyPermanent {
+					return classPermanent
+				}
+				found = classTemporary
+			case classTemporary:
+				found, all = classTemporary, false
+			default:
+				all = false
+			}
+		}
+		if all {
+			return classPermanent
+		}
+		if found != classNone {
+			return found
+		}
+	}
+	return classOf(err)
+}
+
+// allPermanent reports whether err is permanent or wraps a permanent error, where
+// joined errors are only permanent if all of them are permanent.
+func allPermanent(err error) bool {
+	if classOf(err) == classPermanent {
+		return true
+	}
+	switch x := err.(type) {
+	case interface{ Unwrap() error }:
+		return allPermanent(x.Unwrap())
+	case interface{ Unwrap() []error }:
+		errs := x.Unwrap()
+		for _, err := range errs {
+			if !allPermanent(err) {
+				return false
+			}
+		}
+		return len(errs) > 0
+	}
+	return false
+}
+
+// classOf returns the class of err without unwrapping it.
+func classOf(err error) class {
+	if _, ok := err.(*permanentError); ok {
+		return classPermanent
+	}
+	if t, ok := err.(interface{ Temporary() bool }); ok && t.Temporary() {
+		return classTemporary
+	}
+	return classNone
+}
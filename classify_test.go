@@ -0,0 +1,179 @@
+// SPDX-License-Identifier: MIT
+//
+// Copyright 2022 Andrew Bursavich. All rights reserved.
+// Use of this source code is governed by The MIT License
+// which can be found in the LICENSE file.
+
+package retry_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"bursavich.dev/retry"
+	"bursavich.dev/retry/retrytest"
+)
+
+// temporaryError is an error with a Temporary method.
+type temporaryError struct{ err error }
+
+func (e *temporaryError) Error() string   { return e.err.Error() }
+func (e *temporaryError) Unwrap() error   { return e.err }
+func (e *temporaryError) Temporary() bool { return true }
+
+func TestIsPermanentJoin(t *testing.T) {
+	perm := retry.NewPermanentError(errTest)
+	temp := &temporaryError{errTest}
+	tests := []struct {
+		name string
+		err  error
+		// Whether it's permanent with PermanentWins/AnyPermanent, PermanentWins/AllPermanent,
+		// InnermostWins/AnyPermanent, and InnermostWins/AllPermanent.
+		want [4]bool
+	}{
+		{
+			name: "nil",
+			want: [4]bool{false, false, false, false},
+		},
+		{
+			name: "unclassified",
+			err:  errTest,
+			want: [4]bool{false, false, false, false},
+		},
+		{
+			name: "permanent",
+			err:  perm,
+			want: [4]bool{true, true, true, true},
+		},
+		{
+			name: "wrapped permanent",
+			err:  fmt.Errorf("wrapped: %w", perm),
+			want: [4]bool{true, true, true, true},
+		},
+		{
+			name: "temporary",
+			err:  temp,
+			want: [4]bool{false, false, false, false},
+		},
+		{
+			name: "permanent wrapping temporary",
+			err:  retry.NewPermanentError(temp),
+			want: [4]bool{true, true, false, false},
+		},
+		{
+			name: "temporary wrapping permanent",
+			err:  &temporaryError{perm},
+			want: [4]bool{true, true, true, true},
+		},
+		{
+			name: "joined permanent and unclassified",
+			err:  errors.Join(perm, errTest),
+			want: [4]bool{true, false, true, false},
+		},
+		{
+			name: "joined permanent and temporary",
+			err:  errors.Join(perm, temp),
+			want: [4]bool{true, false, true, false},
+		},
+		{
+			name: "joined permanents",
+			err:  errors.Join(perm, fmt.Errorf("wrapped: %w", perm)),
+			want: [4]bool{true, true, true, true},
+		},
+	}
+	modes := []struct {
+		precedence retry.Precedence
+		join       retry.JoinMode
+	}{
+		{retry.PermanentWins, retry.AnyPermanent},
+		{retry.PermanentWins, retry.AllPermanent},
+		{retry.InnermostWins, retry.AnyPermanent},
+		{retry.InnermostWins, retry.AllPermanent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i, m := range modes {
+				if got := retry.IsPermanentJoin(tt.err, m.precedence, m.join); got != tt.want[i] {
+					t.Errorf("IsPermanentJoin(%v, %v): got %v; want %v", m.precedence, m.join, got, tt.want[i])
+				}
+			}
+			if got := retry.IsPermanent(tt.err, retry.InnermostWins); got != tt.want[2] {
+				t.Errorf("IsPermanent(%v): got %v; want %v", retry.InnermostWins, got, tt.want[2])
+			}
+		})
+	}
+}
+
+func TestRetrierClassification(t *testing.T) {
+	r := retry.New(
+		retry.WithMaxRetries(retry.ConstantBackoff(time.Second), 2),
+		retry.WithPrecedence(retry.InnermostWins),
+		retry.WithJoinMode(retry.AllPermanent),
+		retry.WithClock(retrytest.NewAutoClock(time.Now())),
+	)
+	if got, want := r.Precedence(), retry.InnermostWins; got != want {
+		t.Errorf("Precedence: got %v; want %v", got, want)
+	}
+	if got, want := r.JoinMode(), retry.AllPermanent; got != want {
+		t.Errorf("JoinMode: got %v; want %v", got, want)
+	}
+	// The temporary cause wins, so it's retried.
+	attempts := 0
+	err := r.Do(context.Background(), func() error {
+		attempts++
+		return retry.NewPermanentError(&temporaryError{errTest})
+	})
+	checkReason(t, err, retry.ReasonPolicy)
+	if attempts != 3 {
+		t.Errorf("attempts: got %d; want 3", attempts)
+	}
+}
+
+func TestContextDone(t *testing.T) {
+	errCause := errors.New("cause")
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	caused, cancelCause := context.WithCancelCause(context.Background())
+	cancelCause(errCause)
+	tests := []struct {
+		name string
+		ctx  context.Context
+		err  error
+		want bool
+	}{
+		{name: "nil error", ctx: canceled, want: false},
+		{name: "context not done", ctx: context.Background(), err: context.Canceled, want: false},
+		{name: "context error", ctx: canceled, err: fmt.Errorf("call: %w", context.Canceled), want: true},
+		{name: "context cause", ctx: caused, err: fmt.Errorf("call: %w", errCause), want: true},
+		{name: "unrelated error", ctx: canceled, err: errTest, want: false},
+		{name: "unrelated deadline", ctx: canceled, err: context.DeadlineExceeded, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := retry.ContextDone(tt.ctx, tt.err); got != tt.want {
+				t.Errorf("ContextDone: got %v; want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClassifyStrings(t *testing.T) {
+	tests := []struct {
+		got, want string
+	}{
+		{retry.PermanentWins.String(), "permanent wins"},
+		{retry.InnermostWins.String(), "innermost wins"},
+		{retry.Precedence(100).String(), "Precedence(100)"},
+		{retry.AnyPermanent.String(), "any permanent"},
+		{retry.AllPermanent.String(), "all permanent"},
+		{retry.JoinMode(100).String(), "JoinMode(100)"},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("String: got %q; want %q", tt.got, tt.want)
+		}
+	}
+}
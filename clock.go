@@ -0,0 +1,60 @@
+// SPDX-License-Identifier: MIT
+//
+// Copyright 2022 Andrew Bursavich. All rights reserved.
+// Use of this source code is governed by The MIT License
+// which can be found in the LICENSE file.
+
+package retry
+
+import "time"
+
+// A Clock provides time to retry loops. It allows tests to control time
+// so that backoff schedules can be asserted without actually sleeping.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+	// NewTimer returns a new Timer that sends the current time
+	// on its channel after at least duration d.
+	NewTimer(d time.Duration) Timer
+}
+
+// A Timer is a single event timer created by a Clock.
+type Timer interface {
+	// C returns the channel on which the time is delivered.
+	C() <-chan time.Time
+	// Stop prevents the Timer from firing. It returns false
+	// if the timer has already expired or been stopped.
+	Stop() bool
+	// Reset changes the timer to expire after duration d.
+	// It returns true if the timer had been active.
+	Reset(d time.Duration) bool
+}
+
+// SystemClock returns a Clock that uses the system's time.
+func SystemClock() Clock {
+	return systemClock{}
+}
+
+// WithClock returns an Option that sets the Clock used by the Retrier.
+// Context deadlines are compared against the clock's time.
+func WithClock(c Clock) Option {
+	return optionFunc(func(r *Retrier) {
+		if c != nil {
+			r.clock = c
+		}
+	})
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+func (systemClock) NewTimer(d time.Duration) Timer { return systemTimer{time.NewTimer(d)} }
+
+type systemTimer struct{ t *time.Timer }
+
+func (t systemTimer) C() <-chan time.Time { return t.t.C }
+
+func (t systemTimer) Stop() bool { return t.t.Stop() }
+
+func (t systemTimer) Reset(d time.Duration) bool { return t.t.Reset(d) }
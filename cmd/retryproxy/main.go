@@ -0,0 +1,257 @@
+// SPDX-License-Identifier: MIT
+//
+// Copyright 2022 Andrew Bursavich. All rights reserved.
+// Use of this source code is governed by The MIT License
+// which can be found in the LICENSE file.
+
+// Retryproxy generates a retrying proxy for an interface.
+//
+// Go can't implement an interface at run time, so retrying every method of an
+// interface requires generating the proxy ahead of time. Given an interface
+// named Client, the generated code includes a constructor:
+//
+//	func NewRetryingClient(policy retry.Policy, impl Client, classify func(method string, err error) bool) Client
+//
+// Methods whose last result is an error are called according to the policy.
+// If a method's first parameter is a context.Context, it's used for the retry loop.
+// If classify is non-nil and returns false for an error, the error isn't retried.
+//
+// Usage:
+//
+//	//go:generate go run bursavich.dev/retry/cmd/retryproxy -type=Client
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("retryproxy: ")
+
+	typeName := flag.String("type", "", "name of the interface type (required)")
+	output := flag.String("output", "", "output file name (default: <type>_retry.go)")
+	flag.Parse()
+	if *typeName == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	dir := "."
+	if flag.NArg() > 0 {
+		dir = flag.Arg(0)
+	}
+	if *output == "" {
+		*output = strings.ToLower(*typeName) + "_retry.go"
+	}
+
+	pkg, err := loadPackage(dir)
+	if err != nil {
+		log.Fatal(err)
+	}
+	src, err := generate(pkg, *typeName)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, *output), src, 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func loadPackage(dir string) (*types.Package, error) {
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, dir, func(fi os.FileInfo) bool {
+		return !strings.HasSuffix(fi.Name(), "_test.go")
+	}, 0)
+	if err != nil {
+		return nil, err
+	}
+	if len(pkgs) != 1 {
+		return nil, fmt.Errorf("expected one package in %q, found %d", dir, len(pkgs))
+	}
+	var files []*ast.File
+	var name string
+	for _, p := range pkgs {
+		name = p.Name
+		for _, f := range p.Files {
+			files = append(files, f)
+		}
+	}
+	cfg := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	return cfg.Check(name, fset, files, nil)
+}
+
+type generator struct {
+	pkg     *types.Package
+	imports map[string]string // path => name
+	buf     bytes.Buffer
+}
+
+func generate(pkg *types.Package, typeName string) ([]byte, error) {
+	obj := pkg.Scope().Lookup(typeName)
+	if obj == nil {
+		return nil, fmt.Errorf("type %q not found", typeName)
+	}
+	iface, ok := obj.Type().Underlying().(*types.Interface)
+	if !ok {
+		return nil, fmt.Errorf("type %q is not an interface", typeName)
+	}
+
+	g := &generator{
+		pkg: pkg,
+		imports: map[string]string{
+			"bursavich.dev/retry": "retry",
+		},
+	}
+	proxy := "retrying" + typeName
+	g.printf("// %s is a %s that calls the methods of its implementation according to a retry policy.\n", proxy, typeName)
+	g.printf("type %s struct {\n", proxy)
+	g.printf("impl %s\n", typeName)
+	g.printf("r *retry.Retrier\n")
+	g.printf("classify func(method string, err error) bool\n")
+	g.printf("}\n\n")
+
+	g.printf("// NewRetrying%s returns a %s that calls the methods of impl according to the policy.\n", typeName, typeName)
+	g.printf("// If classify is non-nil and returns false for an error, the error isn't retried.\n")
+	g.printf("func NewRetrying%s(policy retry.Policy, impl %s, classify func(method string, err error) bool) %s {\n", typeName, typeName, typeName)
+	g.printf("return &%s{impl: impl, r: retry.New(policy), classify: classify}\n", proxy)
+	g.printf("}\n\n")
+
+	g.printf("func (p *%s) check(method string, err error) error {\n", proxy)
+	g.printf("if err != nil && p.classify != nil && !p.classify(method, err) {\n")
+	g.printf("return retry.NewPermanentError(err)\n")
+	g.printf("}\n")
+	g.printf("return err\n")
+	g.printf("}\n")
+
+	methods := make([]*types.Func, iface.NumMethods())
+	for i := range methods {
+		methods[i] = iface.Method(i)
+	}
+	sort.Slice(methods, func(i, k int) bool { return methods[i].Name() < methods[k].Name() })
+	for _, m := range methods {
+		g.method(proxy, m)
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "// Code generated by retryproxy. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&out, "package %s\n\n", pkg.Name())
+	fmt.Fprintf(&out, "import (\n")
+	paths := make([]string, 0, len(g.imports))
+	for path := range g.imports {
+		paths = append(paths, path)
+	}
+	sort.Slice(paths, func(i, k int) bool {
+		if si, sk := isStd(paths[i]), isStd(paths[k]); si != sk {
+			return si
+		}
+		return paths[i] < paths[k]
+	})
+	for i, path := range paths {
+		if i > 0 && isStd(paths[i-1]) && !isStd(path) {
+			fmt.Fprintf(&out, "\n")
+		}
+		fmt.Fprintf(&out, "%q\n", path)
+	}
+	fmt.Fprintf(&out, ")\n\n")
+	out.Write(g.buf.Bytes())
+	return format.Source(out.Bytes())
+}
+
+func (g *generator) method(proxy string, m *types.Func) {
+	sig := m.Type().(*types.Signature)
+	params, results := sig.Params(), sig.Results()
+
+	var decls, args []string
+	for i := 0; i < params.Len(); i++ {
+		name := fmt.Sprintf("a%d", i)
+		typ := params.At(i).Type()
+		if sig.Variadic() && i == params.Len()-1 {
+			decls = append(decls, name+" ..."+g.typeString(typ.(*types.Slice).Elem()))
+			args = append(args, name+"...")
+			continue
+		}
+		decls = append(decls, name+" "+g.typeString(typ))
+		args = append(args, name)
+	}
+	var resultTypes []string
+	for i := 0; i < results.Len(); i++ {
+		resultTypes = append(resultTypes, g.typeString(results.At(i).Type()))
+	}
+	call := fmt.Sprintf("p.impl.%s(%s)", m.Name(), strings.Join(args, ", "))
+
+	g.printf("\nfunc (p *%s) %s(%s) (%s) {\n", proxy, m.Name(), strings.Join(decls, ", "), strings.Join(resultTypes, ", "))
+	defer g.printf("}\n")
+
+	if results.Len() == 0 || !isError(results.At(results.Len()-1).Type()) {
+		if results.Len() == 0 {
+			g.printf("%s\n", call)
+		} else {
+			g.printf("return %s\n", call)
+		}
+		return
+	}
+
+	ctx := "a0"
+	if params.Len() == 0 || !isContext(params.At(0).Type()) {
+		ctx = "context.Background()"
+		g.imports["context"] = "context"
+	}
+	if results.Len() == 1 {
+		g.printf("return p.r.Do(%s, func() error {\n", ctx)
+		g.printf("return p.check(%q, %s)\n", m.Name(), call)
+		g.printf("})\n")
+		return
+	}
+	var vals []string
+	for i := 0; i < results.Len()-1; i++ {
+		vals = append(vals, fmt.Sprintf("r%d", i))
+		g.printf("var r%d %s\n", i, resultTypes[i])
+	}
+	g.printf("err := p.r.Do(%s, func() error {\n", ctx)
+	g.printf("var err error\n")
+	g.printf("%s, err = %s\n", strings.Join(vals, ", "), call)
+	g.printf("return p.check(%q, err)\n", m.Name())
+	g.printf("})\n")
+	g.printf("return %s, err\n", strings.Join(vals, ", "))
+}
+
+func (g *generator) typeString(t types.Type) string {
+	return types.TypeString(t, func(p *types.Package) string {
+		if p == g.pkg {
+			return ""
+		}
+		g.imports[p.Path()] = p.Name()
+		return p.Name()
+	})
+}
+
+func (g *generator) printf(format string, args ...any) {
+	fmt.Fprintf(&g.buf, format, args...)
+}
+
+func isStd(path string) bool {
+	elem, _, _ := strings.Cut(path, "/")
+	return !strings.Contains(elem, ".")
+}
+
+func isError(t types.Type) bool {
+	return types.Identical(t, types.Universe.Lookup("error").Type())
+}
+
+func isContext(t types.Type) bool {
+	n, ok := t.(*types.Named)
+	return ok && n.Obj().Pkg() != nil && n.Obj().Pkg().Path() == "context" && n.Obj().Name() == "Context"
+}
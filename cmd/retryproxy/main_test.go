@@ -0,0 +1,65 @@
+// SPDX-License-Identifier: MIT
+//
+// Copyright 2022 Andrew Bursavich. All rights reserved.
+// Use of this source code is governed by The MIT License
+// which can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update the golden files")
+
+func TestGenerate(t *testing.T) {
+	tests := []struct {
+		dir      string
+		typeName string
+	}{
+		{dir: "client", typeName: "Client"},
+		{dir: "nocontext", typeName: "Store"}, // doesn't import context
+	}
+	for _, tt := range tests {
+		t.Run(tt.dir, func(t *testing.T) {
+			dir := filepath.Join("testdata", tt.dir)
+			pkg, err := loadPackage(dir)
+			if err != nil {
+				t.Fatalf("loadPackage: %v", err)
+			}
+			got, err := generate(pkg, tt.typeName)
+			if err != nil {
+				t.Fatalf("generate: %v", err)
+			}
+			golden := filepath.Join("testdata", tt.dir+".golden")
+			if *update {
+				if err := os.WriteFile(golden, got, 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("generate: got:\n%s\nwant:\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	pkg, err := loadPackage(filepath.Join("testdata", "client"))
+	if err != nil {
+		t.Fatalf("loadPackage: %v", err)
+	}
+	for _, typeName := range []string{"Missing", "Item"} {
+		if _, err := generate(pkg, typeName); err == nil {
+			t.Errorf("generate(%q): got nil error", typeName)
+		}
+	}
+}
@@ -0,0 +1,26 @@
+// SPDX-License-Identifier: MIT
+//
+// Copyright 2022 Andrew Bursavich. All rights reserved.
+// Use of this source code is governed by The MIT License
+// which can be found in the LICENSE file.
+
+package client
+
+import (
+	"context"
+	"io"
+)
+
+type Item struct {
+	Key   string
+	Value []byte
+}
+
+type Client interface {
+	Get(ctx context.Context, key string) (*Item, error)
+	Put(ctx context.Context, items ...*Item) error
+	Open(name string) (io.ReadCloser, int64, error)
+	Close() error
+	Name() string
+	Reset()
+}
@@ -0,0 +1,96 @@
+// SPDX-License-Identifier: MIT
+//
+// Copyright 2022 Andrew Bursavich. All rights reserved.
+// Use of this source code is governed by The MIT License
+// which can be found in the LICENSE file.
+
+package retry
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+)
+
+// A Coalescer coalesces the backoffs of retry loops that fail against the same key
+// into shared retry waves. Instead of each loop scheduling its own timer, a loop that
+// would wake within the window before an already scheduled wave joins it, which reduces
+// both the number of timers and the size of the burst of retries sent downstream.
+//
+// Joining a wave may lengthen a loop's backoff by up to the window, but never shortens it.
+// It's safe for concurrent use.
+type Coalescer struct {
+	window time.Duration
+
+	mu    sync.Mutex
+	waves map[string][]*wave
+}
+
+type wave struct {
+	wake  time.Time
+	ready chan struct{}
+}
+
+// NewCoalescer returns a new Coalescer with the given window.
+func NewCoalescer(window time.Duration) *Coalescer {
+	return &Coalescer{
+		window: window,
+		waves:  make(map[string][]*wave),
+	}
+}
+
+// WithCoalescing returns an Option that coalesces the Retrier's backoffs into the retry
+// waves of the Coalescer for the given key, such as the name of the downstream dependency.
+// Backoffs that are spun because of WithSpinThreshold aren't coalesced.
+func WithCoalescing(c *Coalescer, key string) Option {
+	return optionFunc(func(r *Retrier) {
+		r.coalescer, r.coalesceKey = c, key
+	})
+}
+
+// wait waits for the wave that the backoff joins and reports whether it was ready
+// before ctx was done.
+func (c *Coalescer) wait(ctx context.Context, clock Clock, key string, d time.Duration) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case <-c.join(clock, key, d):
+		return true
+	}
+}
+
+// join returns the ready channel of the wave that a backoff of d joins,
+// scheduling a new wave if there isn't one within the window.
+func (c *Coalescer) join(clock Clock, key string, d time.Duration) <-chan struct{} {
+	wake := clock.Now().Add(d)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, w := range c.waves[key] {
+		if !w.wake.Before(wake) && w.wake.Sub(wake) <= c.window {
+			return w.ready
+		}
+	}
+	w := &wave{wake: wake, ready: make(chan struct{})}
+	c.waves[key] = append(c.waves[key], w)
+	t := clock.NewTimer(d)
+	go func() {
+		<-t.C()
+		c.release(key, w)
+	}()
+	return w.ready
+}
+
+// release removes the wave and wakes the loops that joined it.
+func (c *Coalescer) release(key string, w *wave) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	waves := slices.DeleteFunc(c.waves[key], func(v *wave) bool { return v == w })
+	if len(waves) == 0 {
+		delete(c.waves, key)
+	} else {
+		c.waves[key] = waves
+	}
+	close(w.ready)
+}
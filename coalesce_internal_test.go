@@ -0,0 +1,65 @@
+// SPDX-License-Identifier: MIT
+//
+// Copyright 2022 Andrew Bursavich. All rights reserved.
+// Use of this source code is governed by The MIT License
+// which can be found in the LICENSE file.
+
+package retry
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestCoalescerJoin(t *testing.T) {
+	c := NewCoalescer(50 * time.Millisecond)
+	clock := systemClock{}
+
+	wave := c.join(clock, "a", 100*time.Millisecond)
+	if joined := c.join(clock, "a", 80*time.Millisecond); joined != wave {
+		t.Error("backoff within the window before the wave: didn't join it")
+	}
+	early := c.join(clock, "a", 10*time.Millisecond)
+	if early == wave {
+		t.Error("backoff outside of the window before the wave: joined it")
+	}
+	if late := c.join(clock, "a", 120*time.Millisecond); late == wave {
+		t.Error("backoff after the wave: joined it")
+	}
+	if other := c.join(clock, "b", 100*time.Millisecond); other == wave {
+		t.Error("backoff with another key: joined the wave")
+	}
+
+	<-early
+	select {
+	case <-wave:
+		t.Error("wave: released with the earlier wave")
+	default:
+	}
+	<-wave
+
+	// All of the waves are eventually released and forgotten.
+	deadline := time.Now().Add(time.Second)
+	for {
+		c.mu.Lock()
+		n := len(c.waves)
+		c.mu.Unlock()
+		if n == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("waves: got %d keys after release; want 0", n)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestCoalescerWaitContextDone(t *testing.T) {
+	c := NewCoalescer(time.Second)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if c.wait(ctx, systemClock{}, "a", time.Hour) {
+		t.Error("wait: got true after ctx was done; want false")
+	}
+}
@@ -0,0 +1,233 @@
+// SPDX-License-Identifier: MIT
+//
+// Copyright 2022 Andrew Bursavich. All rights reserved.
+// Use of this source code is governed by The MIT License
+// which can be found in the LICENSE file.
+
+package retry
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Sequence returns a Policy that uses the first Policy for the first n attempts
+// and the then Policy afterwards, such as fast retries for blips followed by slow
+// retries for outages. The then Policy's attempts are counted from 1 when it takes over.
+func Sequence(first Policy, n int, then Policy) Policy {
+	return &sequence{first: first, n: n, then: then}
+}
+
+type sequence struct {
+	first Policy
+	n     int
+	then  Policy
+}
+
+func (p *sequence) Next(err error, start, now time.Time, attempt int) (time.Duration, bool) {
+	return p.NextState(&State{Err: err, Start: start, Now: now, Attempt: attempt})
+}
+
+func (p *sequence) NextState(s *State) (time.Duration, bool) {
+	if s.Attempt <= p.n {
+		return next(p.first, s)
+	}
+	s.Attempt -= p.n
+	d, ok := next(p.then, s)
+	s.Attempt += p.n
+	return d, ok
+}
+
+func (p *sequence) String() string {
+	return fmt.Sprintf("Sequence(%d)", p.n)
+}
+
+func (p *sequence) parents() []Policy { return []Policy{p.first, p.then} }
+
+func (p *sequence) bounds(attempt int, elapsed time.Duration) (time.Duration, time.Duration, bool) {
+	if attempt <= p.n {
+		return bounds(p.first, attempt, elapsed)
+	}
+	return bounds(p.then, attempt-p.n, elapsed)
+}
+
+// Switch returns a Policy that classifies each error with the classify function and
+// uses the Policy for its class, such as a long backoff for throttling errors and a short
+// one for transient network errors. Errors whose class doesn't have a Policy use the
+// fallback Policy. If the fallback is nil, they aren't retried.
+//
+// The policies share the retry loop's State, so attempts are counted across all classes.
+func Switch(classify func(error) string, policies map[string]Policy, fallback Policy) Policy {
+	p := &switchPolicy{
+		classify: classify,
+		policies: make(map[string]Policy, len(policies)),
+		fallback: fallback,
+	}
+	for class, policy := range policies {
+		p.policies[class] = policy
+		p.classes = append(p.classes, class)
+	}
+	slices.Sort(p.classes)
+	return p
+}
+
+type switchPolicy struct {
+	classify func(error) string
+	policies map[string]Policy
+	classes  []string // sorted
+	fallback Policy
+}
+
+func (p *switchPolicy) Next(err error, start, now time.Time, attempt int) (time.Duration, bool) {
+	return p.NextState(&State{Err: err, Start: start, Now: now, Attempt: attempt})
+}
+
+func (p *switchPolicy) NextState(s *State) (time.Duration, bool) {
+	policy, ok := p.policies[p.classify(s.Err)]
+	if !ok {
+		policy = p.fallback
+	}
+	if policy == nil {
+		return 0, false
+	}
+	return next(policy, s)
+}
+
+func (p *switchPolicy) String() string {
+	return fmt.Sprintf("Switch(%s)", strings.Join(p.classes, ", "))
+}
+
+func (p *switchPolicy) parents() []Policy {
+	parents := make([]Policy, 0, len(p.classes)+1)
+	for _, class := range p.classes {
+		parents = append(parents, p.policies[class])
+	}
+	if p.fallback != nil {
+		parents = append(parents, p.fallback)
+	}
+	return parents
+}
+
+func (p *switchPolicy) bounds(attempt int, elapsed time.Duration) (time.Duration, time.Duration, bool) {
+	// The class of the error isn't known, so the bounds span all of the policies.
+	var min, max time.Duration
+	found := false
+	for _, parent := range p.parents() {
+		lo, hi, ok := bounds(parent, attempt, elapsed)
+		if !ok {
+			continue
+		}
+		if !found || lo < min {
+			min = lo
+		}
+		if !found || hi > max {
+			max = hi
+		}
+		found = true
+	}
+	return min, max, found
+}
+
+// Fastest returns a Policy that uses the shortest of the backoffs of p1 and p2
+// and retries only if both of them allow it.
+//
+// Each of the policies makes its decision with its own copy of the retry loop's
+// State, and only the changes made by the chosen one, such as by SkipTo or
+// SetValue, are kept. If either of them stops, its changes are kept.
+func Fastest(p1, p2 Policy) Policy {
+	return &fastest{p1, p2}
+}
+
+type fastest struct {
+	p1, p2 Policy
+}
+
+func (p *fastest) Next(err error, start, now time.Time, attempt int) (time.Duration, bool) {
+	return p.NextState(&State{Err: err, Start: start, Now: now, Attempt: attempt})
+}
+
+func (p *fastest) NextState(s *State) (time.Duration, bool) {
+	return choose(s, p.p1, p.p2, func(d1, d2 time.Duration) bool { return d1 <= d2 })
+}
+
+func (p *fastest) String() string {
+	return "Fastest"
+}
+
+func (p *fastest) parents() []Policy { return []Policy{p.p1, p.p2} }
+
+func (p *fastest) spec() (Layer, Policy) { return Layer{Type: "fastest", other: p.p2}, p.p1 }
+
+func (p *fastest) bounds(attempt int, elapsed time.Duration) (time.Duration, time.Duration, bool) {
+	min1, max1, ok1 := bounds(p.p1, attempt, elapsed)
+	min2, max2, ok2 := bounds(p.p2, attempt, elapsed)
+	if !ok1 || !ok2 {
+		return 0, 0, false
+	}
+	return min(min1, min2), min(max1, max2), true
+}
+
+// Slowest returns a Policy that uses the longest of the backoffs of p1 and p2
+// and retries only if both of them allow it, so that either of them can stop it,
+// such as a policy with a safety cap. For example, it combines a policy that
+// follows server hints with a policy that sets a floor.
+//
+// Like Fastest, each of the policies makes its decision with its own copy of the
+// retry loop's State, and only the changes made by the chosen one are kept.
+func Slowest(p1, p2 Policy) Policy {
+	return &slowest{p1, p2}
+}
+
+type slowest struct {
+	p1, p2 Policy
+}
+
+func (p *slowest) Next(err error, start, now time.Time, attempt int) (time.Duration, bool) {
+	return p.NextState(&State{Err: err, Start: start, Now: now, Attempt: attempt})
+}
+
+func (p *slowest) NextState(s *State) (time.Duration, bool) {
+	return choose(s, p.p1, p.p2, func(d1, d2 time.Duration) bool { return d1 >= d2 })
+}
+
+func (p *slowest) String() string {
+	return "Slowest"
+}
+
+func (p *slowest) parents() []Policy { return []Policy{p.p1, p.p2} }
+
+func (p *slowest) spec() (Layer, Policy) { return Layer{Type: "slowest", other: p.p2}, p.p1 }
+
+func (p *slowest) bounds(attempt int, elapsed time.Duration) (time.Duration, time.Duration, bool) {
+	min1, max1, ok1 := bounds(p.p1, attempt, elapsed)
+	min2, max2, ok2 := bounds(p.p2, attempt, elapsed)
+	if !ok1 || !ok2 {
+		return 0, 0, false
+	}
+	return max(min1, min2), max(max1, max2), true
+}
+
+// choose evaluates p1 and p2 on forks of s and joins the fork of the policy that stopped,
+// if either of them did, or otherwise of p1 if first reports that its backoff is chosen
+// or of p2 if it isn't.
+func choose(s *State, p1, p2 Policy, first func(d1, d2 time.Duration) bool) (time.Duration, bool) {
+	s1, s2 := s.fork(), s.fork()
+	d1, ok1 := next(p1, s1)
+	d2, ok2 := next(p2, s2)
+	switch {
+	case !ok1:
+		s.join(s1, s1, s2)
+		return 0, false
+	case !ok2:
+		s.join(s2, s1, s2)
+		return 0, false
+	case first(d1, d2):
+		s.join(s1, s1, s2)
+		return d1, true
+	default:
+		s.join(s2, s1, s2)
+		return d2, true
+	}
+}
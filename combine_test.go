@@ -0,0 +1,117 @@
+// SPDX-License-Identifier: MIT
+//
+// Copyright 2022 Andrew Bursavich. All rights reserved.
+// Use of this source code is governed by The MIT License
+// which can be found in the LICENSE file.
+
+package retry_test
+
+import (
+	"testing"
+	"time"
+
+	"bursavich.dev/retry"
+)
+
+func TestFastestSlowest(t *testing.T) {
+	short := retry.ConstantBackoff(time.Second)
+	long := retry.WithMaxRetries(retry.ConstantBackoff(time.Minute), 2)
+	tests := []struct {
+		name   string
+		policy retry.Policy
+		want   time.Duration
+	}{
+		{name: "fastest", policy: retry.Fastest(short, long), want: time.Second},
+		{name: "slowest", policy: retry.Slowest(short, long), want: time.Minute},
+		{name: "fastest reversed", policy: retry.Fastest(long, short), want: time.Second},
+		{name: "slowest reversed", policy: retry.Slowest(long, short), want: time.Minute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for attempt := 1; attempt <= 2; attempt++ {
+				if d, ok := tt.policy.Next(errTest, time.Time{}, time.Time{}, attempt); !ok || d != tt.want {
+					t.Errorf("attempt %d: got (%v, %v); want (%v, true)", attempt, d, ok, tt.want)
+				}
+			}
+			// Either policy stops it.
+			if _, ok := tt.policy.Next(errTest, time.Time{}, time.Time{}, 3); ok {
+				t.Error("attempt 3: retried after the safety cap")
+			}
+			if rows := retry.Table(tt.policy, 10); len(rows) != 2 {
+				t.Errorf("table: got %d rows; want 2", len(rows))
+			}
+		})
+	}
+}
+
+// skipPolicy skips to an attempt and sets a value before returning its backoff.
+type skipPolicy struct {
+	backoff time.Duration
+	to      int
+}
+
+func (p *skipPolicy) Next(err error, start, now time.Time, attempt int) (time.Duration, bool) {
+	return p.backoff, true
+}
+
+func (p *skipPolicy) NextState(s *retry.State) (time.Duration, bool) {
+	s.SkipTo(p.to)
+	s.SetValue(p, true)
+	return p.backoff, true
+}
+
+// observePolicy records the attempt and the value set by a skipPolicy that it observes.
+type observePolicy struct {
+	backoff time.Duration
+	skip    *skipPolicy
+	attempt int
+	value   any
+}
+
+func (p *observePolicy) Next(err error, start, now time.Time, attempt int) (time.Duration, bool) {
+	return p.backoff, true
+}
+
+func (p *observePolicy) NextState(s *retry.State) (time.Duration, bool) {
+	p.attempt, p.value = s.Attempt, s.Value(p.skip)
+	return p.backoff, true
+}
+
+func TestFastestSlowestState(t *testing.T) {
+	tests := []struct {
+		name    string
+		combine func(p1, p2 retry.Policy) retry.Policy
+		skip    time.Duration
+		observe time.Duration
+		chosen  bool // whether the skipPolicy is chosen
+	}{
+		{name: "fastest chooses skip", combine: retry.Fastest, skip: time.Second, observe: time.Minute, chosen: true},
+		{name: "fastest chooses observe", combine: retry.Fastest, skip: time.Minute, observe: time.Second},
+		{name: "slowest chooses skip", combine: retry.Slowest, skip: time.Minute, observe: time.Second, chosen: true},
+		{name: "slowest chooses observe", combine: retry.Slowest, skip: time.Second, observe: time.Minute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			skip := &skipPolicy{backoff: tt.skip, to: 5}
+			observe := &observePolicy{backoff: tt.observe, skip: skip}
+			p := tt.combine(skip, observe).(retry.StatePolicy)
+
+			s := &retry.State{Err: errTest, Attempt: 1}
+			if _, ok := p.NextState(s); !ok {
+				t.Fatal("NextState: got false; want true")
+			}
+			// The policies don't observe each other's changes.
+			if observe.attempt != 1 || observe.value != nil {
+				t.Errorf("observed: got attempt %d and value %v; want attempt 1 and value <nil>", observe.attempt, observe.value)
+			}
+			// Only the chosen policy's changes are kept.
+			wantAttempt, wantValue := 1, any(nil)
+			if tt.chosen {
+				wantAttempt, wantValue = 5, true
+			}
+			if s.Attempt != wantAttempt || s.Value(skip) != wantValue {
+				t.Errorf("state: got attempt %d and value %v; want attempt %d and value %v", s.Attempt, s.Value(skip), wantAttempt, wantValue)
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+// SPDX-License-Identifier: MIT
+//
+// Copyright 2022 Andrew Bursavich. All rights reserved.
+// Use of this source code is governed by The MIT License
+// which can be found in the LICENSE file.
+
+package retry
+
+import "time"
+
+// Error is the error returned by a retry loop that stops without a successful attempt.
+// It wraps the last error, which can be unwrapped with errors.Is and errors.As, and its
+// message is the last error's message. It provides programmatic access to why and when
+// retrying stopped.
+type Error struct {
+	err      error
+	reason   Reason
+	attempts int
+	elapsed  time.Duration
+	limit    Limit
+}
+
+// Error returns the message of the last error.
+func (e *Error) Error() string { return e.err.Error() }
+
+// Unwrap returns the last error.
+func (e *Error) Unwrap() error { return e.err }
+
+// Reason returns the reason the retry loop stopped.
+func (e *Error) Reason() Reason { return e.reason }
+
+// Attempts returns the number of attempts made by the retry loop.
+func (e *Error) Attempts() int { return e.attempts }
+
+// Elapsed returns the elapsed duration of the retry loop.
+func (e *Error) Elapsed() time.Duration { return e.elapsed }
+
+// Limit returns the Limit that stopped the policy from retrying, if the policy was
+// stopped by WithMaxRetries, WithMaxElapsedDuration, or WithLimits, or LimitNone
+// otherwise. If limits are nested, it's the first Limit that was reached.
+func (e *Error) Limit() Limit { return e.limit }
@@ -0,0 +1,51 @@
+// SPDX-License-Identifier: MIT
+//
+// Copyright 2022 Andrew Bursavich. All rights reserved.
+// Use of this source code is governed by The MIT License
+// which can be found in the LICENSE file.
+
+package retry
+
+import (
+	"context"
+	"errors"
+)
+
+// A Queue durably accepts operations for long-horizon background retries,
+// such as a job scheduler or a durable message queue.
+type Queue interface {
+	// Enqueue accepts a serialized descriptor of an operation and the error
+	// that caused it to be escalated, and returns an ID for tracking it.
+	Enqueue(ctx context.Context, op []byte, cause error) (id string, err error)
+}
+
+// Escalation is a handle for tracking an operation that was escalated to a Queue.
+type Escalation struct {
+	// ID is the tracking ID returned by the Queue.
+	ID string
+	// Cause is the last error returned by the operation before it was escalated.
+	Cause error
+}
+
+// DoOrEscalate executes the retriable function according to the given policy and,
+// if retrying stops without success, escalates the operation by handing its serialized
+// descriptor to the queue. It bridges synchronous in-process retries with asynchronous
+// background retries.
+//
+// If fn succeeds, it returns a nil Escalation and a nil error. If the operation is escalated,
+// it returns an Escalation and a nil error. If fn returns a permanent error, ctx is canceled,
+// or the queue fails, it returns a nil Escalation and the error.
+//
+// The queue is called with a context that isn't canceled when ctx is,
+// so an operation can be escalated after its deadline is exceeded.
+func DoOrEscalate(ctx context.Context, policy Policy, fn func() error, q Queue, op []byte) (*Escalation, error) {
+	err := Do(ctx, policy, fn)
+	if err == nil || isPermErr(err) || errors.Is(ctx.Err(), context.Canceled) {
+		return nil, err
+	}
+	id, qerr := q.Enqueue(context.WithoutCancel(ctx), op, err)
+	if qerr != nil {
+		return nil, errors.Join(err, qerr)
+	}
+	return &Escalation{ID: id, Cause: err}, nil
+}
@@ -0,0 +1,105 @@
+// SPDX-License-Identifier: MIT
+//
+// Copyright 2022 Andrew Bursavich. All rights reserved.
+// Use of this source code is governed by The MIT License
+// which can be found in the LICENSE file.
+
+package retry
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// WithExplanation returns an Option that collects the decisions made by each retry loop
+// and passes them to fn when the loop stops. It explains which layer of a composed policy
+// produced or modified each backoff and which layer stopped retrying, which is invaluable
+// when debugging deeply composed policies.
+//
+// Layers are included if they're policies provided by this package or if their parent
+// calls them as a StatePolicy. It adds overhead to every decision, so it's intended for debugging.
+func WithExplanation(fn func(*Explanation)) Option {
+	return optionFunc(func(r *Retrier) {
+		r.explain = fn
+	})
+}
+
+// Explanation explains the decisions made by a retry loop.
+type Explanation struct {
+	// Decisions are the decisions made after each failed attempt.
+	Decisions []Decision
+	// Outcome describes why the retry loop stopped.
+	Outcome string
+}
+
+// Decision is a decision made by a Policy after a failed attempt.
+type Decision struct {
+	// Attempt is the number of the retry attempt being decided, starting at 1.
+	Attempt int
+	// Err is the error returned by the failed attempt.
+	Err error
+	// Steps are the results of each layer of the policy, from innermost to outermost.
+	Steps []Step
+	// Backoff is the backoff duration chosen by the policy.
+	Backoff time.Duration
+	// Retry indicates if the policy allowed a retry.
+	Retry bool
+}
+
+// Step is the result of a single layer of a Policy.
+type Step struct {
+	// Policy describes the layer.
+	Policy string
+	// Backoff is the backoff duration returned by the layer.
+	Backoff time.Duration
+	// Retry indicates if the layer allowed a retry.
+	Retry bool
+}
+
+func (s Step) String() string {
+	if !s.Retry {
+		return s.Policy + ": stop"
+	}
+	return fmt.Sprintf("%s: %v", s.Policy, s.Backoff)
+}
+
+// String returns a human-readable explanation.
+func (e *Explanation) String() string {
+	var b strings.Builder
+	for _, d := range e.Decisions {
+		fmt.Fprintf(&b, "attempt %d failed: %v\n", d.Attempt, d.Err)
+		stopped := false
+		for _, s := range d.Steps {
+			note := ""
+			if !s.Retry && !stopped {
+				note = " <- stopped retrying"
+				stopped = true
+			}
+			fmt.Fprintf(&b, "\t%v%s\n", s, note)
+		}
+		if d.Retry {
+			fmt.Fprintf(&b, "\tretry after %v\n", d.Backoff)
+		} else {
+			fmt.Fprintf(&b, "\tdon't retry\n")
+		}
+	}
+	fmt.Fprintf(&b, "outcome: %s", e.Outcome)
+	return b.String()
+}
+
+// trace records the result of the policy if the State is being traced.
+func (s *State) trace(p Policy, d time.Duration, ok bool) {
+	if s.steps == nil {
+		return
+	}
+	*s.steps = append(*s.steps, Step{Policy: policyName(p), Backoff: d, Retry: ok})
+}
+
+// policyName returns a description of the policy.
+func policyName(p Policy) string {
+	if s, ok := p.(fmt.Stringer); ok {
+		return s.String()
+	}
+	return fmt.Sprintf("%T", p)
+}
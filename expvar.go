@@ -0,0 +1,88 @@
+// SPDX-License-Identifier: MIT
+//
+// Copyright 2022 Andrew Bursavich. All rights reserved.
+// Use of this source code is governed by The MIT License
+// which can be found in the LICENSE file.
+
+package retry
+
+import (
+	"context"
+	"expvar"
+	"sync"
+	"time"
+)
+
+var (
+	expvarOnce sync.Once
+	expvarMap  *expvar.Map
+	expvarMu   sync.Mutex
+)
+
+// WithExpvar returns an Option that publishes counts of the Retrier's attempts, successes,
+// and give-ups with expvar, such as for quick triage through the /debug/vars endpoint
+// of binaries that don't use other metrics libraries.
+//
+// The counts are published in the "retry" map under the given name. Retriers with the same
+// name share their counts. The map is only published if the Option is used. If a map named
+// "retry" was already published, such as by another package, the counts are added to it.
+// If another kind of variable was published with the name, the counts aren't published.
+func WithExpvar(name string) Option {
+	return WithObserver(newExpvarObserver(name))
+}
+
+type expvarObserver struct {
+	attempts  *expvar.Int
+	successes *expvar.Int
+	giveUps   *expvar.Int
+}
+
+func newExpvarObserver(name string) *expvarObserver {
+	expvarOnce.Do(func() {
+		switch v := expvar.Get("retry").(type) {
+		case *expvar.Map:
+			expvarMap = v // Published by someone else.
+		case nil:
+			expvarMap = expvar.NewMap("retry")
+		default:
+			expvarMap = new(expvar.Map) // Unpublished, since the name is taken.
+		}
+	})
+	expvarMu.Lock()
+	defer expvarMu.Unlock()
+	m, ok := expvarMap.Get(name).(*expvar.Map)
+	if !ok {
+		m = new(expvar.Map)
+		expvarMap.Set(name, m)
+	}
+	return &expvarObserver{
+		attempts:  expvarInt(m, "attempts"),
+		successes: expvarInt(m, "successes"),
+		giveUps:   expvarInt(m, "give_ups"),
+	}
+}
+
+func expvarInt(m *expvar.Map, key string) *expvar.Int {
+	if v, ok := m.Get(key).(*expvar.Int); ok {
+		return v
+	}
+	v := new(expvar.Int)
+	m.Set(key, v)
+	return v
+}
+
+func (o *expvarObserver) AttemptStart(ctx context.Context, a Attempt) {
+	o.attempts.Add(1)
+}
+
+func (o *expvarObserver) AttemptEnd(ctx context.Context, a Attempt, err error) {
+	if err == nil {
+		o.successes.Add(1)
+	}
+}
+
+func (o *expvarObserver) Backoff(ctx context.Context, a Attempt, err error, d time.Duration) {}
+
+func (o *expvarObserver) GiveUp(ctx context.Context, a Attempt, reason Reason, err error) {
+	o.giveUps.Add(1)
+}
@@ -0,0 +1,42 @@
+// SPDX-License-Identifier: MIT
+//
+// Copyright 2022 Andrew Bursavich. All rights reserved.
+// Use of this source code is governed by The MIT License
+// which can be found in the LICENSE file.
+
+package retry_test
+
+import (
+	"context"
+	"expvar"
+	"testing"
+	"time"
+
+	"bursavich.dev/retry"
+	"bursavich.dev/retry/retrytest"
+)
+
+func TestExpvarPublishedMap(t *testing.T) {
+	// The map may already be published by another package.
+	m, ok := expvar.Get("retry").(*expvar.Map)
+	if !ok {
+		m = expvar.NewMap("retry")
+	}
+	r := retry.New(
+		retry.WithMaxRetries(retry.ConstantBackoff(time.Second), 1),
+		retry.WithClock(retrytest.NewAutoClock(time.Now())),
+		retry.WithExpvar("published"),
+	)
+	_ = r.Do(context.Background(), func() error { return errTest })
+
+	counts, ok := m.Get("published").(*expvar.Map)
+	if !ok {
+		t.Fatal("counts weren't published in the existing map")
+	}
+	if got := counts.Get("attempts").String(); got != "2" {
+		t.Errorf("attempts: got %s; want 2", got)
+	}
+	if got := counts.Get("give_ups").String(); got != "1" {
+		t.Errorf("give_ups: got %s; want 1", got)
+	}
+}
@@ -1,3 +1,3 @@
 module bursavich.dev/retry
 
-go 1.22
+go 1.23
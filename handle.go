@@ -0,0 +1,150 @@
+// SPDX-License-Identifier: MIT
+//
+// Copyright 2022 Andrew Bursavich. All rights reserved.
+// Use of this source code is governed by The MIT License
+// which can be found in the LICENSE file.
+
+package retry
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// Handle is a handle for monitoring and canceling a retry loop that runs in the background.
+type Handle struct {
+	cancel   context.CancelFunc
+	done     chan struct{}
+	progress chan Progress
+	attempts atomic.Int64
+	err      error
+}
+
+// Go executes the retriable function according to the given policy and Retrier options
+// in a new goroutine and returns a Handle for monitoring it. The retry loop uses a context
+// derived from ctx, which is canceled when the loop finishes or the Handle is canceled.
+func Go(ctx context.Context, policy Policy, fn func() error, opts ...Option) *Handle {
+	return goRun(ctx, New(policy, opts...), fn, loop{})
+}
+
+// goRun runs the retry loop in a new goroutine. The loop's function and progress are
+// set from fn and the Handle. If l's progress is non-nil, it's called with the loop's
+// progress in addition to the Handle's subscriber.
+func goRun(ctx context.Context, r *Retrier, fn func() error, l loop) *Handle {
+	ctx, cancel := context.WithCancel(ctx)
+	h := &Handle{
+		cancel:   cancel,
+		done:     make(chan struct{}),
+		progress: make(chan Progress, progressBuffer),
+	}
+	go func() {
+		defer close(h.done)
+		defer close(h.progress)
+		defer cancel()
+		progress := l.progress
+		l.fn = func(context.Context) error {
+			h.attempts.Add(1)
+			return fn()
+		}
+		l.progress = func(p Progress) {
+			if progress != nil {
+				progress(p)
+			}
+			h.report(p)
+		}
+		h.err = r.run(ctx, l)
+	}()
+	return h
+}
+
+// progressBuffer is the number of Progress events that are buffered for a Handle's subscriber.
+const progressBuffer = 16
+
+// Progress returns a channel on which the progress of the retry loop is delivered,
+// such as for rendering "retrying (attempt 3, next in 12s)" in a user interface.
+// The channel is closed when the loop finishes.
+//
+// Events are buffered, but they're dropped rather than blocking the retry loop
+// if the buffer is full. The channel should be consumed by a single subscriber.
+func (h *Handle) Progress() <-chan Progress {
+	return h.progress
+}
+
+func (h *Handle) report(p Progress) {
+	select {
+	case h.progress <- p:
+	default:
+	}
+}
+
+// Done returns a channel that's closed when the retry loop finishes.
+func (h *Handle) Done() <-chan struct{} {
+	return h.done
+}
+
+// Err returns the error returned by the retry loop. It returns nil if the loop
+// hasn't finished yet or if it finished successfully.
+func (h *Handle) Err() error {
+	select {
+	case <-h.done:
+		return h.err
+	default:
+		return nil
+	}
+}
+
+// Wait waits for the retry loop to finish and returns its error.
+func (h *Handle) Wait() error {
+	<-h.done
+	return h.err
+}
+
+// Attempts returns the number of attempts that have been started.
+func (h *Handle) Attempts() int {
+	return int(h.attempts.Load())
+}
+
+// Cancel cancels the retry loop. It doesn't wait for the loop to finish.
+func (h *Handle) Cancel() {
+	h.cancel()
+}
+
+// DoThenBackground executes the retriable function according to the given policy and
+// Retrier options, blocking for up to k attempts, which is at least 1. If the function
+// succeeds or retrying stops within k attempts, it returns a nil Handle and the result,
+// like Do. Otherwise, it returns the latest error along with a Handle for the same retry
+// loop, which continues in the background. It's a pragmatic pattern for write paths with
+// durable follow-up.
+//
+// The background loop isn't canceled when ctx is canceled, so it must be canceled
+// with the Handle if it shouldn't run until it finishes. If ctx is done while blocking,
+// it returns the Handle and the context's error.
+func DoThenBackground(ctx context.Context, policy Policy, k int, fn func() error, opts ...Option) (*Handle, error) {
+	var (
+		mu   sync.Mutex
+		last error
+	)
+	k = max(k, 1)
+	waiting := make(chan struct{})
+	h := goRun(context.WithoutCancel(ctx), New(policy, opts...), fn, loop{progress: func(p Progress) {
+		switch {
+		case p.Kind == AttemptFailed:
+			mu.Lock()
+			last = p.Err
+			mu.Unlock()
+		case p.Kind == BackingOff && p.Attempt == k+1:
+			close(waiting)
+		}
+	}})
+	select {
+	case <-h.Done():
+		return nil, h.Err()
+	case <-waiting:
+		mu.Lock()
+		defer mu.Unlock()
+		return h, last
+	case <-ctx.Done():
+		return h, ctx.Err()
+	}
+}
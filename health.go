@@ -0,0 +1,164 @@
+// SPDX-License-Identifier: MIT
+//
+// Copyright 2022 Andrew Bursavich. All rights reserved.
+// Use of this source code is governed by The MIT License
+// which can be found in the LICENSE file.
+
+package retry
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"bursavich.dev/retry/internal/hooks"
+)
+
+// A HealthOption configures a Health registry.
+type HealthOption interface {
+	apply(*Health)
+}
+
+type healthOptionFunc func(*Health)
+
+func (fn healthOptionFunc) apply(h *Health) { fn(h) }
+
+// WithRamp returns a HealthOption that spreads the release of the loops that are parked
+// on a key uniformly over the ramp window when the key is marked up, instead of releasing
+// them all at once, to avoid a synchronized reconnect stampede.
+func WithRamp(window time.Duration) HealthOption {
+	return healthOptionFunc(func(h *Health) {
+		h.ramp = window
+	})
+}
+
+// Health is a registry of the health of dependencies, identified by keys. External signals,
+// such as health checks or service discovery, mark keys as down or up, and policies wrapped
+// with WithHealth react to them. Keys are up until they're marked down.
+//
+// It's safe for concurrent use.
+type Health struct {
+	ramp time.Duration
+
+	mu   sync.Mutex
+	down map[string]chan struct{} // closed when the key is marked up
+}
+
+// NewHealth returns a new Health registry with the given options.
+func NewHealth(opts ...HealthOption) *Health {
+	h := &Health{down: make(map[string]chan struct{})}
+	for _, o := range opts {
+		o.apply(h)
+	}
+	return h
+}
+
+// SetDown marks the key as down.
+func (h *Health) SetDown(key string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.down[key]; !ok {
+		h.down[key] = make(chan struct{})
+	}
+}
+
+// SetUp marks the key as up and releases the loops that are parked on it.
+func (h *Health) SetUp(key string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if up, ok := h.down[key]; ok {
+		close(up)
+		delete(h.down, key)
+	}
+}
+
+// IsDown reports whether the key is marked down.
+func (h *Health) IsDown(key string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, ok := h.down[key]
+	return ok
+}
+
+// Wait parks until the key is up and returns nil, or until ctx is done and returns
+// the context's error. If the key was down, its release is delayed by a random
+// duration within the ramp window.
+func (h *Health) Wait(ctx context.Context, key string) error {
+	return h.wait(ctx, key, systemClock{})
+}
+
+// wait is like Wait, but it times the ramp with the clock.
+func (h *Health) wait(ctx context.Context, key string, clock Clock) error {
+	h.mu.Lock()
+	up, ok := h.down[key]
+	h.mu.Unlock()
+	if !ok {
+		return nil
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-up:
+	}
+	if h.ramp <= 0 {
+		return nil
+	}
+	d := rand.N(h.ramp)
+	if _, ok := clock.(systemClock); ok {
+		hooks.Sleep(d)
+	}
+	t := clock.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C():
+		return nil
+	}
+}
+
+// WithHealthGate returns an Option that parks the Retrier's loops after each backoff
+// while the key is marked down in the Health registry, and releases them when it's
+// marked up, spread over the registry's ramp window. The ramp is timed by the Retrier's Clock.
+func WithHealthGate(h *Health, key string) Option {
+	return optionFunc(func(r *Retrier) {
+		r.gate, r.gateKey = h, key
+	})
+}
+
+// WithHealth returns a Policy that wraps the parent Policy and stops retrying without
+// sleeping while the key is marked down in the Health registry, so that callers fail fast
+// instead of waiting for a dependency that's known to be unavailable. It behaves like the
+// parent while the key is up.
+func WithHealth(parent Policy, h *Health, key string) Policy {
+	return &withHealth{parent: parent, h: h, key: key}
+}
+
+type withHealth struct {
+	parent Policy
+	h      *Health
+	key    string
+}
+
+func (p *withHealth) Next(err error, start, now time.Time, attempt int) (time.Duration, bool) {
+	return p.NextState(&State{Err: err, Start: start, Now: now, Attempt: attempt})
+}
+
+func (p *withHealth) NextState(s *State) (time.Duration, bool) {
+	if p.h.IsDown(p.key) {
+		return 0, false
+	}
+	return next(p.parent, s)
+}
+
+func (p *withHealth) String() string {
+	return fmt.Sprintf("WithHealth(%q)", p.key)
+}
+
+func (p *withHealth) parents() []Policy { return []Policy{p.parent} }
+
+func (p *withHealth) bounds(attempt int, elapsed time.Duration) (time.Duration, time.Duration, bool) {
+	return bounds(p.parent, attempt, elapsed)
+}
@@ -0,0 +1,116 @@
+// SPDX-License-Identifier: MIT
+//
+// Copyright 2022 Andrew Bursavich. All rights reserved.
+// Use of this source code is governed by The MIT License
+// which can be found in the LICENSE file.
+
+package retry_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"bursavich.dev/retry"
+	"bursavich.dev/retry/retrytest"
+)
+
+func TestHealth(t *testing.T) {
+	h := retry.NewHealth()
+	if h.IsDown("a") {
+		t.Error("IsDown: got true for a new key; want false")
+	}
+	h.SetDown("a")
+	h.SetDown("a")
+	if !h.IsDown("a") {
+		t.Error("IsDown: got false after SetDown; want true")
+	}
+	if h.IsDown("b") {
+		t.Error("IsDown: got true for another key; want false")
+	}
+	h.SetUp("a")
+	h.SetUp("a")
+	if h.IsDown("a") {
+		t.Error("IsDown: got true after SetUp; want false")
+	}
+}
+
+func TestWithHealth(t *testing.T) {
+	h := retry.NewHealth()
+	p := retry.WithHealth(retry.ConstantBackoff(time.Second), h, "a")
+	if d, ok := p.Next(errTest, time.Time{}, time.Time{}, 1); !ok || d != time.Second {
+		t.Errorf("up: got (%v, %v); want (1s, true)", d, ok)
+	}
+	h.SetDown("a")
+	if _, ok := p.Next(errTest, time.Time{}, time.Time{}, 1); ok {
+		t.Error("down: got true; want false")
+	}
+}
+
+func TestHealthWait(t *testing.T) {
+	h := retry.NewHealth()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := h.Wait(ctx, "a"); err != nil {
+		t.Errorf("up: got %v; want nil", err)
+	}
+
+	h.SetDown("a")
+	done := make(chan error, 1)
+	go func() { done <- h.Wait(ctx, "a") }()
+	select {
+	case err := <-done:
+		t.Fatalf("down: returned %v while the key is down", err)
+	case <-time.After(10 * time.Millisecond):
+	}
+	h.SetUp("a")
+	if err := <-done; err != nil {
+		t.Errorf("released: got %v; want nil", err)
+	}
+
+	h.SetDown("a")
+	cancel()
+	if err := h.Wait(ctx, "a"); !errors.Is(err, context.Canceled) {
+		t.Errorf("canceled: got %v; want %v", err, context.Canceled)
+	}
+}
+
+func TestHealthGate(t *testing.T) {
+	const ramp = time.Minute
+	h := retry.NewHealth(retry.WithRamp(ramp))
+	h.SetDown("a")
+
+	start := time.Now()
+	clock := retrytest.NewAutoClock(start)
+	r := retry.New(
+		retry.WithMaxRetries(retry.ConstantBackoff(time.Second), 1),
+		retry.WithClock(clock),
+		retry.WithHealthGate(h, "a"),
+	)
+	attempts := make(chan int, 2)
+	done := make(chan error, 1)
+	go func() {
+		n := 0
+		done <- r.Do(context.Background(), func() error {
+			n++
+			attempts <- n
+			return errTest
+		})
+	}()
+	<-attempts
+	select {
+	case <-attempts:
+		t.Fatal("attempt: retried while the key is down")
+	case <-time.After(10 * time.Millisecond):
+	}
+	h.SetUp("a")
+	<-attempts
+	if err := <-done; !errors.Is(err, errTest) {
+		t.Errorf("Do: got %v; want %v", err, errTest)
+	}
+	// The release is delayed by up to the ramp after the backoff.
+	if elapsed := clock.Now().Sub(start); elapsed < time.Second || elapsed >= time.Second+ramp {
+		t.Errorf("elapsed: got %v; want [1s, %v)", elapsed, time.Second+ramp)
+	}
+}
@@ -0,0 +1,29 @@
+// SPDX-License-Identifier: MIT
+//
+// Copyright 2022 Andrew Bursavich. All rights reserved.
+// Use of this source code is governed by The MIT License
+// which can be found in the LICENSE file.
+
+// Package hooks provides process-wide hooks into retry loops for testing.
+package hooks
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+var sleep atomic.Pointer[func(time.Duration)]
+
+// SetSleep sets a function that's called whenever a retry loop is about to sleep
+// and returns a function that restores the previous one.
+func SetSleep(fn func(time.Duration)) (restore func()) {
+	prev := sleep.Swap(&fn)
+	return func() { sleep.Store(prev) }
+}
+
+// Sleep calls the sleep hook, if any.
+func Sleep(d time.Duration) {
+	if fn := sleep.Load(); fn != nil && *fn != nil {
+		(*fn)(d)
+	}
+}
@@ -0,0 +1,71 @@
+// SPDX-License-Identifier: MIT
+//
+// Copyright 2022 Andrew Bursavich. All rights reserved.
+// Use of this source code is governed by The MIT License
+// which can be found in the LICENSE file.
+
+package retry
+
+import (
+	"context"
+	"errors"
+	"iter"
+)
+
+// ErrExhausted is yielded by Attempts when the policy stops retrying.
+var ErrExhausted = errors.New("retry: policy stopped retrying")
+
+// Attempts returns an iterator over attempts made according to the given policy and
+// Retrier options. It lets the caller control the body of the retry loop, including
+// breaking early or inspecting intermediate state, while it handles backoff and
+// cancellation.
+//
+// It yields the number of each attempt, starting at 1, and a nil error. The loop body
+// makes the attempt and breaks when it succeeds; otherwise, the iterator waits for
+// the policy's backoff before yielding the next attempt. The policy isn't given the
+// errors of the attempts.
+//
+// If retrying stops, it yields the number of the last attempt and a non-nil error:
+// ErrExhausted if the policy, as tightened by WithTightening, stopped retrying,
+// context.DeadlineExceeded if the next attempt would exceed the context's deadline,
+// or the context's error if it's done.
+//
+//	for attempt, err := range retry.Attempts(ctx, policy) {
+//		if err != nil {
+//			return err
+//		}
+//		if err := call(ctx); err == nil {
+//			break
+//		}
+//	}
+func Attempts(ctx context.Context, policy Policy, opts ...Option) iter.Seq2[int, error] {
+	r := New(policy, opts...)
+	return func(yield func(int, error) bool) {
+		var t Timer
+		start := r.clock.Now()
+		deadline, hasDeadline := ctx.Deadline()
+		tight := tighteningFrom(ctx)
+		s := State{Start: start}
+		for n := 1; ; n++ {
+			if !yield(n, nil) {
+				return
+			}
+			s.Now = r.clock.Now()
+			s.Attempt++
+			backoff, ok := next(r.policy, &s)
+			if !ok || !tight.allows(n) {
+				yield(n, ErrExhausted)
+				return
+			}
+			backoff = tight.cap(backoff)
+			if hasDeadline && deadline.Before(r.clock.Now().Add(backoff)) {
+				yield(n, context.DeadlineExceeded)
+				return
+			}
+			if !r.wait(ctx, &t, backoff) {
+				yield(n, ctx.Err())
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,103 @@
+// SPDX-License-Identifier: MIT
+//
+// Copyright 2022 Andrew Bursavich. All rights reserved.
+// Use of this source code is governed by The MIT License
+// which can be found in the LICENSE file.
+
+package retry
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// learnerWeight is the weight of the latest observation in a Learner's moving average.
+const learnerWeight = 0.2
+
+// Learner executes retriable functions according to a Policy and learns, per key,
+// how long failures typically take to clear, from the first failed attempt to the first
+// successful one. Subsequent retry loops for the key raise the policy's backoffs to at
+// least a fraction of the estimate, so that incidents with long recovery times don't
+// spend their early retries on futile attempts.
+//
+// The estimate is an exponentially weighted moving average of the observed durations.
+//
+// It's safe for concurrent use.
+type Learner struct {
+	policy   Policy
+	fraction float64
+	opts     []Option
+
+	mu        sync.Mutex
+	estimates map[string]time.Duration
+}
+
+// NewLearner returns a new Learner with the given policy, fraction of the estimate
+// used as the minimum backoff, and Retrier options. If fraction isn't in (0, 1],
+// it defaults to 0.1.
+func NewLearner(policy Policy, fraction float64, opts ...Option) *Learner {
+	if fraction <= 0 || fraction > 1 {
+		fraction = 0.1
+	}
+	return &Learner{
+		policy:    policy,
+		fraction:  fraction,
+		opts:      opts,
+		estimates: make(map[string]time.Duration),
+	}
+}
+
+// Do executes the retriable function for the key according to the Learner's policy.
+// It has the same semantics as Retrier.Do.
+func (l *Learner) Do(ctx context.Context, key string, fn func() error) error {
+	policy := l.policy
+	if min := l.MinBackoff(key); min > 0 {
+		policy = WithMinBackoff(policy, min)
+	}
+	r := New(policy, l.opts...)
+	var first time.Time // end of the first failed attempt
+	err := r.Do(ctx, func() error {
+		err := fn()
+		if err != nil && first.IsZero() {
+			first = r.clock.Now()
+		}
+		return err
+	})
+	if err == nil && !first.IsZero() {
+		l.observe(key, r.clock.Now().Sub(first))
+	}
+	return err
+}
+
+// Estimate returns the estimated duration for failures of the key to clear
+// and a bool indicating if there is an estimate.
+func (l *Learner) Estimate(key string) (time.Duration, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	d, ok := l.estimates[key]
+	return d, ok
+}
+
+// MinBackoff returns the minimum backoff proposed for the key,
+// or zero if there is no estimate.
+func (l *Learner) MinBackoff(key string) time.Duration {
+	d, _ := l.Estimate(key)
+	return time.Duration(float64(d) * l.fraction)
+}
+
+// Reset forgets the estimate for the key.
+func (l *Learner) Reset(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.estimates, key)
+}
+
+func (l *Learner) observe(key string, d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if prev, ok := l.estimates[key]; ok {
+		d = time.Duration(learnerWeight*float64(d) + (1-learnerWeight)*float64(prev))
+	}
+	l.estimates[key] = d
+}
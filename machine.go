@@ -0,0 +1,105 @@
+// SPDX-License-Identifier: MIT
+//
+// Copyright 2022 Andrew Bursavich. All rights reserved.
+// Use of this source code is governed by The MIT License
+// which can be found in the LICENSE file.
+
+package retry
+
+// A MachineState is a state of the retry loop's state machine.
+type MachineState string
+
+// States of the retry loop's state machine.
+const (
+	// MachineDelaying is the initial state in which the loop waits for the initial delay
+	// configured by WithInitialDelay. It's left for MachineAttempting immediately if there
+	// isn't one.
+	MachineDelaying MachineState = "delaying"
+	// MachineGated is the state in which the loop waits for the health gate configured by
+	// WithHealthGate to allow the next attempt. It's left immediately if there isn't one.
+	MachineGated MachineState = "gated"
+	// MachineAttempting is the state in which an attempt is made.
+	MachineAttempting MachineState = "attempting"
+	// MachineDeciding is the state in which the policy decides whether and when to retry.
+	MachineDeciding MachineState = "deciding"
+	// MachineBackingOff is the state in which the loop waits for the backoff duration.
+	MachineBackingOff MachineState = "backing_off"
+	// MachineSucceeded is the final state after a successful attempt.
+	MachineSucceeded MachineState = "succeeded"
+	// MachineGaveUp is the final state after the loop stopped without a successful attempt.
+	MachineGaveUp MachineState = "gave_up"
+	// MachineInterrupted is the final state after the loop was stopped without giving up
+	// by its caller, such as DoValueCached serving a cached value.
+	MachineInterrupted MachineState = "interrupted"
+)
+
+// A Transition is a transition of the retry loop's state machine.
+//
+// The guard is a boolean expression over the following terms:
+//
+//	delay       whether an initial delay is configured by WithInitialDelay
+//	err         the error returned by the latest attempt, or nil
+//	permanent   whether err is permanent according to the Retrier's Precedence
+//	interrupted whether the loop's caller stopped it after err
+//	retry       whether the policy, as tightened by WithTightening, allowed a retry,
+//	            including any backpressure
+//	fits        whether the next attempt would be made before the context's deadline,
+//	            if any, after the backoff, including any backpressure
+//	extended    whether the deadline was extended to fit the next attempt
+//	last_chance whether a last-chance attempt is scheduled before the deadline
+//	            because of WithLastChance
+//	done        whether the context was done before the wait of the state ended
+type Transition struct {
+	From  MachineState `json:"from"`
+	To    MachineState `json:"to"`
+	Guard string       `json:"guard"`
+	// Reason is the reason the loop gives up, if the transition is to MachineGaveUp.
+	Reason Reason `json:"reason,omitempty"`
+}
+
+// A Machine is a machine-readable description of the retry loop's state machine,
+// such as for formal verification or model-based testing of a resilience stack.
+// Each state's outgoing transitions have mutually exclusive guards that cover all cases.
+type Machine struct {
+	Initial     MachineState   `json:"initial"`
+	States      []MachineState `json:"states"`
+	Final       []MachineState `json:"final"`
+	Transitions []Transition   `json:"transitions"`
+}
+
+// StateMachine returns a description of the state machine implemented by the retry loop.
+// For example, it can be checked that no transition leaves MachineAttempting to retry
+// when permanent is true.
+func StateMachine() Machine {
+	return Machine{
+		Initial: MachineDelaying,
+		States: []MachineState{
+			MachineDelaying,
+			MachineGated,
+			MachineAttempting,
+			MachineDeciding,
+			MachineBackingOff,
+			MachineSucceeded,
+			MachineGaveUp,
+			MachineInterrupted,
+		},
+		Final: []MachineState{MachineSucceeded, MachineGaveUp, MachineInterrupted},
+		// These must be kept in sync with Retrier.run.
+		Transitions: []Transition{
+			{From: MachineDelaying, To: MachineAttempting, Guard: "!delay"},
+			{From: MachineDelaying, To: MachineGaveUp, Guard: "delay && done", Reason: ReasonContextDone},
+			{From: MachineDelaying, To: MachineGated, Guard: "delay && !done"},
+			{From: MachineGated, To: MachineGaveUp, Guard: "done", Reason: ReasonContextDone},
+			{From: MachineGated, To: MachineAttempting, Guard: "!done"},
+			{From: MachineAttempting, To: MachineSucceeded, Guard: "err == nil"},
+			{From: MachineAttempting, To: MachineGaveUp, Guard: "err != nil && permanent", Reason: ReasonPermanent},
+			{From: MachineAttempting, To: MachineInterrupted, Guard: "err != nil && !permanent && interrupted"},
+			{From: MachineAttempting, To: MachineDeciding, Guard: "err != nil && !permanent && !interrupted"},
+			{From: MachineDeciding, To: MachineGaveUp, Guard: "!retry", Reason: ReasonPolicy},
+			{From: MachineDeciding, To: MachineGaveUp, Guard: "retry && !fits && !extended && !last_chance", Reason: ReasonDeadline},
+			{From: MachineDeciding, To: MachineBackingOff, Guard: "retry && (fits || extended || last_chance)"},
+			{From: MachineBackingOff, To: MachineGaveUp, Guard: "done", Reason: ReasonContextDone},
+			{From: MachineBackingOff, To: MachineGated, Guard: "!done"},
+		},
+	}
+}
@@ -0,0 +1,36 @@
+// SPDX-License-Identifier: MIT
+//
+// Copyright 2022 Andrew Bursavich. All rights reserved.
+// Use of this source code is governed by The MIT License
+// which can be found in the LICENSE file.
+
+package retry_test
+
+import (
+	"slices"
+	"testing"
+
+	"bursavich.dev/retry"
+)
+
+func TestStateMachine(t *testing.T) {
+	m := retry.StateMachine()
+	if !slices.Contains(m.States, m.Initial) {
+		t.Errorf("initial state %q isn't a state", m.Initial)
+	}
+	outgoing := make(map[retry.MachineState]int)
+	for _, tr := range m.Transitions {
+		if !slices.Contains(m.States, tr.From) || !slices.Contains(m.States, tr.To) {
+			t.Errorf("transition %q -> %q: unknown state", tr.From, tr.To)
+		}
+		if (tr.To == retry.MachineGaveUp) != (tr.Reason != retry.ReasonNone) {
+			t.Errorf("transition %q -> %q: unexpected reason %v", tr.From, tr.To, tr.Reason)
+		}
+		outgoing[tr.From]++
+	}
+	for _, s := range m.States {
+		if final := slices.Contains(m.Final, s); final != (outgoing[s] == 0) {
+			t.Errorf("state %q: final is %v, but it has %d outgoing transitions", s, final, outgoing[s])
+		}
+	}
+}
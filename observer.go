@@ -0,0 +1,96 @@
+// SPDX-License-Identifier: MIT
+//
+// Copyright 2022 Andrew Bursavich. All rights reserved.
+// Use of this source code is governed by The MIT License
+// which can be found in the LICENSE file.
+
+package retry
+
+import (
+	"context"
+	"strconv"
+	"time"
+)
+
+// An Observer observes the lifecycle events of retry loops, such as for logging or metrics.
+// Its methods are called synchronously by the retry loop with the loop's context,
+// so they should return quickly. An Observer that's shared by many Retriers must be
+// safe for concurrent use.
+type Observer interface {
+	// AttemptStart is called before an attempt is made.
+	AttemptStart(ctx context.Context, a Attempt)
+	// AttemptEnd is called after an attempt is made with the error it returned, if any.
+	AttemptEnd(ctx context.Context, a Attempt, err error)
+	// Backoff is called after a failed attempt, with the error it returned,
+	// before waiting for the backoff duration.
+	Backoff(ctx context.Context, a Attempt, err error, d time.Duration)
+	// GiveUp is called when the retry loop stops without a successful attempt,
+	// with the reason it stopped and the last error.
+	GiveUp(ctx context.Context, a Attempt, reason Reason, err error)
+}
+
+// WithObserver returns an Option that adds an Observer of the Retrier's retry loops.
+// If multiple observers are added, they're called in the order in which they were added.
+func WithObserver(o Observer) Option {
+	return optionFunc(func(r *Retrier) {
+		if o != nil {
+			r.observers = append(r.observers, o)
+		}
+	})
+}
+
+// A finisher is an Observer that's notified when a retry loop finishes for any reason,
+// including successes and interruptions.
+type finisher interface {
+	finish(ctx context.Context, a Attempt, reason Reason, err error)
+}
+
+// A Reason is the reason a retry loop stopped without a successful attempt.
+type Reason int
+
+// Reasons a retry loop may stop.
+const (
+	// ReasonNone indicates that the retry loop didn't give up.
+	ReasonNone Reason = iota
+	// ReasonPermanent indicates that an attempt returned a permanent error.
+	ReasonPermanent
+	// ReasonPolicy indicates that the policy, or the context's tightening of it, stopped retrying.
+	ReasonPolicy
+	// ReasonDeadline indicates that the next attempt would exceed the context's deadline.
+	ReasonDeadline
+	// ReasonContextDone indicates that the context was done while backing off.
+	ReasonContextDone
+)
+
+var reasonNames = [...]string{
+	ReasonNone:        "none",
+	ReasonPermanent:   "permanent error",
+	ReasonPolicy:      "policy stopped retrying",
+	ReasonDeadline:    "next attempt would exceed the deadline",
+	ReasonContextDone: "context done",
+}
+
+func (r Reason) String() string {
+	if r < 0 || int(r) >= len(reasonNames) {
+		return "Reason(" + strconv.Itoa(int(r)) + ")"
+	}
+	return reasonNames[r]
+}
+
+var reasonLabels = [...]string{
+	ReasonNone:        "none",
+	ReasonPermanent:   "permanent",
+	ReasonPolicy:      "policy_exhausted",
+	ReasonDeadline:    "deadline",
+	ReasonContextDone: "context_done",
+}
+
+// Label returns a short snake_case identifier of the reason, such as "policy_exhausted",
+// that's suitable for metric labels and attribute values. Unlike the description returned
+// by String, it won't change.
+func (r Reason) Label() string {
+	if r < 0 || int(r) >= len(reasonLabels) {
+		return "reason_" + strconv.Itoa(int(r))
+	}
+	return reasonLabels[r]
+}
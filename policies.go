@@ -7,8 +7,13 @@
 package retry
 
 import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
 	"math"
 	"math/rand/v2"
+	"strconv"
+	"sync"
 	"time"
 )
 
@@ -62,6 +67,86 @@ func Immediately() Policy {
 	return immediately
 }
 
+// Forever returns a Policy that retries indefinitely, such as for reconnect loops.
+// Its backoff starts at min, doubles up to max, and uses the default jitter,
+// but it's never more than max.
+//
+// If an attempt lasts for at least max, such as a connection that was established
+// and later dropped, it's considered stable and the backoff resets to min.
+//
+// If min isn't positive, it defaults to DefaultMinBackoff. If max isn't positive,
+// it defaults to DefaultMaxBackoff. If max is less than min, it's raised to min.
+func Forever(min, max time.Duration) Policy {
+	if min <= 0 {
+		min = DefaultMinBackoff
+	}
+	if max <= 0 {
+		max = DefaultMaxBackoff
+	}
+	if max < min {
+		max = min
+	}
+	return &forever{
+		min:    min,
+		max:    max,
+		parent: WithDefaultRandomJitter(ExponentialBackoff(min, max, 2)),
+	}
+}
+
+type forever struct {
+	min    time.Duration
+	max    time.Duration
+	parent Policy
+}
+
+// foreverState is the per-loop state of a Forever policy.
+type foreverState struct {
+	wake time.Time // scheduled start of the latest attempt
+	base int       // attempts before the latest reset
+}
+
+func (p *forever) Next(err error, start, now time.Time, attempt int) (time.Duration, bool) {
+	return p.NextState(&State{Err: err, Start: start, Now: now, Attempt: attempt})
+}
+
+func (p *forever) NextState(s *State) (time.Duration, bool) {
+	fs, ok := s.Value(p).(foreverState)
+	if !ok {
+		fs.wake = s.Start
+	}
+	if s.Now.Sub(fs.wake) >= p.max {
+		fs.base = s.Attempt - 1
+	}
+	attempt := s.Attempt
+	s.Attempt -= fs.base
+	d, _ := next(p.parent, s)
+	d = min(d, p.max)
+	s.Attempt = attempt
+	fs.wake = s.Now.Add(d)
+	s.SetValue(p, fs)
+	return d, true
+}
+
+func (p *forever) String() string {
+	return fmt.Sprintf("Forever(%v, %v)", p.min, p.max)
+}
+
+func (p *forever) spec() (Layer, Policy) {
+	return Layer{Type: "forever", Params: map[string]float64{
+		"min": p.min.Seconds(),
+		"max": p.max.Seconds(),
+	}}, nil
+}
+
+func (p *forever) jitter() {}
+
+func (p *forever) bounds(attempt int, elapsed time.Duration) (time.Duration, time.Duration, bool) {
+	// The backoff may have been reset, so it may be as low as the first backoff.
+	lo, _, _ := bounds(p.parent, 1, elapsed)
+	_, hi, _ := bounds(p.parent, attempt, elapsed)
+	return min(lo, p.max), min(hi, p.max), true
+}
+
 // ConstantBackoff returns a Policy that uses a constant backoff duration.
 func ConstantBackoff(backoff time.Duration) Policy {
 	return constantBackoff{backoff}
@@ -75,6 +160,18 @@ func (p constantBackoff) Next(err error, start, now time.Time, attempt int) (tim
 	return time.Duration(p.backoff), true
 }
 
+func (p constantBackoff) String() string {
+	return fmt.Sprintf("ConstantBackoff(%v)", p.backoff)
+}
+
+func (p constantBackoff) spec() (Layer, Policy) {
+	return Layer{Type: "constant", Params: map[string]float64{"backoff": p.backoff.Seconds()}}, nil
+}
+
+func (p constantBackoff) bounds(attempt int, elapsed time.Duration) (time.Duration, time.Duration, bool) {
+	return p.backoff, p.backoff, true
+}
+
 // ExponentialBackoff returns a Policy in which the backoff grows exponentially.
 // The backoff will start at the min and will be scaled by the growth factor
 // for each successive attempt until it's capped at the max.
@@ -136,6 +233,263 @@ func (p *exponentialBackoff) Next(err error, start, now time.Time, attempt int)
 	return time.Duration(backoff), true
 }
 
+func (p *exponentialBackoff) String() string {
+	return fmt.Sprintf("ExponentialBackoff(%v, %v, %v)", p.min, p.max, p.factor)
+}
+
+func (p *exponentialBackoff) spec() (Layer, Policy) {
+	return Layer{Type: "exponential", Params: map[string]float64{
+		"min":    p.min.Seconds(),
+		"max":    p.max.Seconds(),
+		"factor": p.factor,
+	}}, nil
+}
+
+func (p *exponentialBackoff) bounds(attempt int, elapsed time.Duration) (time.Duration, time.Duration, bool) {
+	d, _ := p.Next(nil, time.Time{}, time.Time{}, attempt)
+	return d, d, true
+}
+
+// LinearBackoff returns a Policy in which the backoff grows linearly.
+// The backoff will start at start and will grow by step for each successive
+// attempt until it's capped at max.
+//
+// If start isn't positive, it defaults to DefaultMinBackoff. If step isn't positive,
+// it defaults to start. If max isn't positive, it defaults to DefaultMaxBackoff.
+func LinearBackoff(start, step, max time.Duration) Policy {
+	if start <= 0 {
+		start = DefaultMinBackoff
+	}
+	if step <= 0 {
+		step = start
+	}
+	if max <= 0 {
+		max = DefaultMaxBackoff
+	}
+	return &linearBackoff{start: start, step: step, max: max}
+}
+
+type linearBackoff struct {
+	start time.Duration
+	step  time.Duration
+	max   time.Duration
+}
+
+func (p *linearBackoff) Next(err error, start, now time.Time, attempt int) (time.Duration, bool) {
+	backoff := float64(p.start) + float64(attempt-1)*float64(p.step)
+	if float64(p.max) < backoff {
+		return p.max, true
+	}
+	return time.Duration(backoff), true
+}
+
+func (p *linearBackoff) String() string {
+	return fmt.Sprintf("LinearBackoff(%v, %v, %v)", p.start, p.step, p.max)
+}
+
+func (p *linearBackoff) spec() (Layer, Policy) {
+	return Layer{Type: "linear", Params: map[string]float64{
+		"start": p.start.Seconds(),
+		"step":  p.step.Seconds(),
+		"max":   p.max.Seconds(),
+	}}, nil
+}
+
+func (p *linearBackoff) bounds(attempt int, elapsed time.Duration) (time.Duration, time.Duration, bool) {
+	d, _ := p.Next(nil, time.Time{}, time.Time{}, attempt)
+	return d, d, true
+}
+
+// PolynomialBackoff returns a Policy in which the backoff grows polynomially.
+// The backoff will be base×attempt^exponent, capped at max. For example,
+// an exponent of 2 results in quadratic backoff.
+//
+// If base isn't positive, it defaults to DefaultMinBackoff. If exponent isn't positive,
+// it defaults to 2. If max isn't positive, it defaults to DefaultMaxBackoff.
+func PolynomialBackoff(base time.Duration, exponent float64, max time.Duration) Policy {
+	if base <= 0 {
+		base = DefaultMinBackoff
+	}
+	if exponent <= 0 || math.IsNaN(exponent) {
+		exponent = 2
+	}
+	if max <= 0 {
+		max = DefaultMaxBackoff
+	}
+	return &polynomialBackoff{base: base, exponent: exponent, max: max}
+}
+
+type polynomialBackoff struct {
+	base     time.Duration
+	exponent float64
+	max      time.Duration
+}
+
+func (p *polynomialBackoff) Next(err error, start, now time.Time, attempt int) (time.Duration, bool) {
+	backoff := float64(p.base) * math.Pow(float64(attempt), p.exponent)
+	if float64(p.max) < backoff {
+		return p.max, true
+	}
+	return time.Duration(backoff), true
+}
+
+func (p *polynomialBackoff) String() string {
+	return fmt.Sprintf("PolynomialBackoff(%v, %v, %v)", p.base, p.exponent, p.max)
+}
+
+func (p *polynomialBackoff) spec() (Layer, Policy) {
+	return Layer{Type: "polynomial", Params: map[string]float64{
+		"base":     p.base.Seconds(),
+		"exponent": p.exponent,
+		"max":      p.max.Seconds(),
+	}}, nil
+}
+
+func (p *polynomialBackoff) bounds(attempt int, elapsed time.Duration) (time.Duration, time.Duration, bool) {
+	d, _ := p.Next(nil, time.Time{}, time.Time{}, attempt)
+	return d, d, true
+}
+
+// ExhaustMode selects the behavior of ScheduleBackoff after its durations are exhausted.
+type ExhaustMode int
+
+// Modes of ScheduleBackoff after its durations are exhausted.
+const (
+	// ExhaustStop stops retrying.
+	ExhaustStop ExhaustMode = iota
+	// ExhaustRepeat repeats the last duration.
+	ExhaustRepeat
+	// ExhaustLoop starts again from the first duration.
+	ExhaustLoop
+)
+
+var exhaustModeNames = [...]string{
+	ExhaustStop:   "stop",
+	ExhaustRepeat: "repeat",
+	ExhaustLoop:   "loop",
+}
+
+func (m ExhaustMode) String() string {
+	if m < 0 || int(m) >= len(exhaustModeNames) {
+		return "ExhaustMode(" + strconv.Itoa(int(m)) + ")"
+	}
+	return exhaustModeNames[m]
+}
+
+// ScheduleBackoff returns a Policy in which the backoff for each attempt is taken
+// from an explicit schedule of durations, such as a hand-tuned schedule from a runbook.
+// The mode selects the behavior after the durations are exhausted. If there are
+// no durations, it never retries.
+func ScheduleBackoff(durations []time.Duration, onExhausted ExhaustMode) Policy {
+	return &scheduleBackoff{durations: append([]time.Duration(nil), durations...), mode: onExhausted}
+}
+
+type scheduleBackoff struct {
+	durations []time.Duration
+	mode      ExhaustMode
+}
+
+func (p *scheduleBackoff) Next(err error, start, now time.Time, attempt int) (time.Duration, bool) {
+	n := len(p.durations)
+	i := attempt - 1
+	switch {
+	case n == 0 || i < 0:
+		return 0, false
+	case i < n:
+		return p.durations[i], true
+	case p.mode == ExhaustRepeat:
+		return p.durations[n-1], true
+	case p.mode == ExhaustLoop:
+		return p.durations[i%n], true
+	default:
+		return 0, false
+	}
+}
+
+func (p *scheduleBackoff) String() string {
+	return fmt.Sprintf("ScheduleBackoff(%v, %v)", p.durations, p.mode)
+}
+
+func (p *scheduleBackoff) bounds(attempt int, elapsed time.Duration) (time.Duration, time.Duration, bool) {
+	d, ok := p.Next(nil, time.Time{}, time.Time{}, attempt)
+	return d, d, ok
+}
+
+// ProportionalBackoff returns a Policy in which the backoff is the fraction of the time
+// remaining before the retry loop's deadline, bounded by min and max. It adapts the pace
+// of retries to callers with very long or very short deadlines. For example, with a fraction
+// of 0.1, a caller with 30s remaining waits for 3s and a caller with 500ms remaining waits
+// for 50ms.
+//
+// The deadline is only provided to policies by a Retrier configured with WithPolicyDeadline.
+// Without a deadline, the backoff is the max.
+//
+// If fraction isn't positive or is NaN, it defaults to 0.1, and if it's greater than 1,
+// it's capped at 1. If min is negative, it's raised to zero. If max isn't positive,
+// it defaults to DefaultMaxBackoff. If max is less than min, it's raised to min.
+func ProportionalBackoff(fraction float64, min, max time.Duration) Policy {
+	switch {
+	case fraction <= 0 || math.IsNaN(fraction):
+		fraction = defaultProportionalFraction
+	case fraction > 1:
+		fraction = 1
+	}
+	if min < 0 {
+		min = 0
+	}
+	if max <= 0 {
+		max = DefaultMaxBackoff
+	}
+	if max < min {
+		max = min
+	}
+	return &proportionalBackoff{fraction: fraction, min: min, max: max}
+}
+
+// defaultProportionalFraction is the default fraction of a ProportionalBackoff.
+const defaultProportionalFraction = 0.1
+
+type proportionalBackoff struct {
+	fraction float64
+	min      time.Duration
+	max      time.Duration
+}
+
+func (p *proportionalBackoff) Next(err error, start, now time.Time, attempt int) (time.Duration, bool) {
+	return p.NextState(&State{Err: err, Start: start, Now: now, Attempt: attempt})
+}
+
+func (p *proportionalBackoff) NextState(s *State) (time.Duration, bool) {
+	if s.Deadline.IsZero() {
+		return p.max, true
+	}
+	d := time.Duration(p.fraction * float64(s.Deadline.Sub(s.Now)))
+	switch {
+	case d < p.min:
+		return p.min, true
+	case d > p.max:
+		return p.max, true
+	default:
+		return d, true
+	}
+}
+
+func (p *proportionalBackoff) String() string {
+	return fmt.Sprintf("ProportionalBackoff(%v, %v, %v)", p.fraction, p.min, p.max)
+}
+
+func (p *proportionalBackoff) spec() (Layer, Policy) {
+	return Layer{Type: "proportional", Params: map[string]float64{
+		"fraction": p.fraction,
+		"min":      p.min.Seconds(),
+		"max":      p.max.Seconds(),
+	}}, nil
+}
+
+func (p *proportionalBackoff) bounds(attempt int, elapsed time.Duration) (time.Duration, time.Duration, bool) {
+	return p.min, p.max, true
+}
+
 // WithRandomJitter returns a Policy that wraps the parent Policy and adds or subtracts
 // random jitter as a factor of its backoff. For example, with a factor of 0.5
 // and a parent backoff of 10s, the randomized backoff would be in [5s, 15s].
@@ -158,11 +512,18 @@ type withRandomJitter struct {
 }
 
 func (p *withRandomJitter) Next(err error, start, now time.Time, attempt int) (time.Duration, bool) {
-	d, allow := p.parent.Next(err, start, now, attempt)
+	return p.NextState(&State{Err: err, Start: start, Now: now, Attempt: attempt})
+}
+
+func (p *withRandomJitter) NextState(s *State) (time.Duration, bool) {
+	d, allow := next(p.parent, s)
 	if !allow {
 		return 0, false
 	}
-	r := rand.Float64()
+	if s.exact {
+		return d, true
+	}
+	r := s.float64()
 	// r = [0, 1)
 	// 2*r = [0, 2)
 	// 2*r - 1 = [-1, 1)
@@ -172,39 +533,1008 @@ func (p *withRandomJitter) Next(err error, start, now time.Time, attempt int) (t
 	return time.Duration(float64(d) * (1 + (p.factor * (2*r - 1)))), true
 }
 
-// WithMaxRetries returns a Policy that wraps the parent Policy and sets a limit
-// for the total number of retry attempts.
-func WithMaxRetries(parent Policy, limit int) Policy {
-	return &maxRetries{parent, limit}
+func (p *withRandomJitter) String() string {
+	return fmt.Sprintf("WithRandomJitter(%v)", p.factor)
 }
 
-type maxRetries struct {
+func (p *withRandomJitter) spec() (Layer, Policy) {
+	return Layer{Type: "random_jitter", Params: map[string]float64{"factor": p.factor}}, p.parent
+}
+
+func (p *withRandomJitter) parents() []Policy { return []Policy{p.parent} }
+
+func (p *withRandomJitter) jitter() {}
+
+func (p *withRandomJitter) bounds(attempt int, elapsed time.Duration) (time.Duration, time.Duration, bool) {
+	min, max, ok := bounds(p.parent, attempt, elapsed)
+	if !ok {
+		return 0, 0, false
+	}
+	return time.Duration(float64(min) * (1 - p.factor)), time.Duration(float64(max) * (1 + p.factor)), true
+}
+
+// WithBoundedJitter returns a Policy that wraps the parent Policy and adds or subtracts
+// random jitter as a factor of its backoff, like WithRandomJitter, and clamps the result
+// to [min, max]. The result is never negative, regardless of the factor or the parent's
+// backoff. For example, with a factor of 0.5, bounds of [1s, 12s], and a parent backoff
+// of 10s, the randomized backoff would be in [5s, 12s].
+func WithBoundedJitter(parent Policy, factor float64, min, max time.Duration) Policy {
+	if factor <= 0 || factor > 1 {
+		factor = DefaultJitterFactor
+	}
+	min = time.Duration(math.Max(0, float64(min)))
+	if max < min {
+		max = min
+	}
+	return &boundedJitter{parent: parent, factor: factor, min: min, max: max}
+}
+
+type boundedJitter struct {
 	parent Policy
-	limit  int
+	factor float64
+	min    time.Duration
+	max    time.Duration
 }
 
-func (p *maxRetries) Next(err error, start, now time.Time, attempt int) (time.Duration, bool) {
-	if attempt > p.limit {
+func (p *boundedJitter) Next(err error, start, now time.Time, attempt int) (time.Duration, bool) {
+	return p.NextState(&State{Err: err, Start: start, Now: now, Attempt: attempt})
+}
+
+func (p *boundedJitter) NextState(s *State) (time.Duration, bool) {
+	d, allow := next(p.parent, s)
+	if !allow {
 		return 0, false
 	}
-	return p.parent.Next(err, start, now, attempt)
+	if !s.exact {
+		d = time.Duration(float64(d) * (1 + (p.factor * (2*s.float64() - 1))))
+	}
+	return p.clamp(d), true
 }
 
-// WithMaxElapsedDuration returns a Policy that wraps the parent Policy and sets a limit
-// for the total elapsed duration in which retries are allowed.
-func WithMaxElapsedDuration(parent Policy, limit time.Duration) Policy {
-	return &maxElapsed{parent, limit}
+func (p *boundedJitter) clamp(d time.Duration) time.Duration {
+	if d < p.min {
+		return p.min
+	}
+	if d > p.max {
+		return p.max
+	}
+	return d
 }
 
-type maxElapsed struct {
+func (p *boundedJitter) String() string {
+	return fmt.Sprintf("WithBoundedJitter(%v, %v, %v)", p.factor, p.min, p.max)
+}
+
+func (p *boundedJitter) spec() (Layer, Policy) {
+	return Layer{Type: "bounded_jitter", Params: map[string]float64{
+		"factor": p.factor,
+		"min":    p.min.Seconds(),
+		"max":    p.max.Seconds(),
+	}}, p.parent
+}
+
+func (p *boundedJitter) parents() []Policy { return []Policy{p.parent} }
+
+func (p *boundedJitter) jitter() {}
+
+func (p *boundedJitter) bounds(attempt int, elapsed time.Duration) (time.Duration, time.Duration, bool) {
+	min, max, ok := bounds(p.parent, attempt, elapsed)
+	if !ok {
+		return 0, 0, false
+	}
+	min = p.clamp(time.Duration(float64(min) * (1 - p.factor)))
+	max = p.clamp(time.Duration(float64(max) * (1 + p.factor)))
+	return min, max, true
+}
+
+// WithFullJitter returns a Policy that wraps the parent Policy and replaces its backoff
+// with a random duration in [0, backoff]. For example, with a parent backoff of 10s,
+// the randomized backoff would be in [0s, 10s]. It decorrelates clients more than
+// WithRandomJitter, such as under thundering-herd conditions, at the cost of
+// occasionally retrying immediately.
+func WithFullJitter(parent Policy) Policy {
+	return &fullJitter{parent}
+}
+
+type fullJitter struct {
 	parent Policy
-	limit  time.Duration
 }
 
-func (p *maxElapsed) Next(err error, start, now time.Time, attempt int) (time.Duration, bool) {
-	d, ok := p.parent.Next(err, start, now, attempt)
-	if start.Add(p.limit).Before(now.Add(d)) {
+func (p *fullJitter) Next(err error, start, now time.Time, attempt int) (time.Duration, bool) {
+	return p.NextState(&State{Err: err, Start: start, Now: now, Attempt: attempt})
+}
+
+func (p *fullJitter) NextState(s *State) (time.Duration, bool) {
+	d, allow := next(p.parent, s)
+	if !allow {
+		return 0, false
+	}
+	if s.exact {
+		return d, true
+	}
+	return time.Duration(float64(d) * s.float64()), true
+}
+
+func (p *fullJitter) String() string {
+	return "WithFullJitter()"
+}
+
+func (p *fullJitter) spec() (Layer, Policy) {
+	return Layer{Type: "full_jitter"}, p.parent
+}
+
+func (p *fullJitter) parents() []Policy { return []Policy{p.parent} }
+
+func (p *fullJitter) jitter() {}
+
+func (p *fullJitter) bounds(attempt int, elapsed time.Duration) (time.Duration, time.Duration, bool) {
+	_, max, ok := bounds(p.parent, attempt, elapsed)
+	if !ok {
+		return 0, 0, false
+	}
+	return 0, max, true
+}
+
+// WithEqualJitter returns a Policy that wraps the parent Policy and replaces its backoff
+// with half of it plus a random duration in [0, backoff/2]. For example, with a parent
+// backoff of 10s, the randomized backoff would be in [5s, 10s]. It guarantees a minimum
+// wait of half the backoff while still spreading retries.
+func WithEqualJitter(parent Policy) Policy {
+	return &equalJitter{parent}
+}
+
+type equalJitter struct {
+	parent Policy
+}
+
+func (p *equalJitter) Next(err error, start, now time.Time, attempt int) (time.Duration, bool) {
+	return p.NextState(&State{Err: err, Start: start, Now: now, Attempt: attempt})
+}
+
+func (p *equalJitter) NextState(s *State) (time.Duration, bool) {
+	d, allow := next(p.parent, s)
+	if !allow {
 		return 0, false
 	}
+	if s.exact {
+		return d, true
+	}
+	half := float64(d) / 2
+	return time.Duration(half + half*s.float64()), true
+}
+
+func (p *equalJitter) String() string {
+	return "WithEqualJitter()"
+}
+
+func (p *equalJitter) spec() (Layer, Policy) {
+	return Layer{Type: "equal_jitter"}, p.parent
+}
+
+func (p *equalJitter) parents() []Policy { return []Policy{p.parent} }
+
+func (p *equalJitter) jitter() {}
+
+func (p *equalJitter) bounds(attempt int, elapsed time.Duration) (time.Duration, time.Duration, bool) {
+	min, max, ok := bounds(p.parent, attempt, elapsed)
+	if !ok {
+		return 0, 0, false
+	}
+	return min / 2, max, true
+}
+
+// WithMaxBackoff returns a Policy that wraps the parent Policy and caps its backoffs at max.
+// It's a generic ceiling for composed policies, such as when jitter that's applied to
+// an ExponentialBackoff could push the backoff above its max.
+func WithMaxBackoff(parent Policy, max time.Duration) Policy {
+	return &maxBackoff{parent, max}
+}
+
+type maxBackoff struct {
+	parent Policy
+	max    time.Duration
+}
+
+func (p *maxBackoff) Next(err error, start, now time.Time, attempt int) (time.Duration, bool) {
+	return p.NextState(&State{Err: err, Start: start, Now: now, Attempt: attempt})
+}
+
+func (p *maxBackoff) NextState(s *State) (time.Duration, bool) {
+	d, ok := next(p.parent, s)
+	if d > p.max {
+		d = p.max
+	}
 	return d, ok
 }
+
+func (p *maxBackoff) String() string {
+	return fmt.Sprintf("WithMaxBackoff(%v)", p.max)
+}
+
+func (p *maxBackoff) spec() (Layer, Policy) {
+	return Layer{Type: "max_backoff", Params: map[string]float64{"max": p.max.Seconds()}}, p.parent
+}
+
+func (p *maxBackoff) parents() []Policy { return []Policy{p.parent} }
+
+func (p *maxBackoff) bounds(attempt int, elapsed time.Duration) (time.Duration, time.Duration, bool) {
+	min, max, ok := bounds(p.parent, attempt, elapsed)
+	if !ok {
+		return 0, 0, false
+	}
+	if min > p.max {
+		min = p.max
+	}
+	if max > p.max {
+		max = p.max
+	}
+	return min, max, true
+}
+
+// WithMinBackoff returns a Policy that wraps the parent Policy and raises its backoffs to
+// at least min, including negative backoffs produced by jitter. It protects downstream
+// services from accidental hot-looping when a composed policy yields near-zero backoffs.
+func WithMinBackoff(parent Policy, min time.Duration) Policy {
+	return &minBackoff{parent, min}
+}
+
+type minBackoff struct {
+	parent Policy
+	min    time.Duration
+}
+
+func (p *minBackoff) Next(err error, start, now time.Time, attempt int) (time.Duration, bool) {
+	return p.NextState(&State{Err: err, Start: start, Now: now, Attempt: attempt})
+}
+
+func (p *minBackoff) NextState(s *State) (time.Duration, bool) {
+	d, ok := next(p.parent, s)
+	if d < p.min {
+		d = p.min
+	}
+	return d, ok
+}
+
+func (p *minBackoff) String() string {
+	return fmt.Sprintf("WithMinBackoff(%v)", p.min)
+}
+
+func (p *minBackoff) spec() (Layer, Policy) {
+	return Layer{Type: "min_backoff", Params: map[string]float64{"min": p.min.Seconds()}}, p.parent
+}
+
+func (p *minBackoff) parents() []Policy { return []Policy{p.parent} }
+
+func (p *minBackoff) bounds(attempt int, elapsed time.Duration) (time.Duration, time.Duration, bool) {
+	min, max, ok := bounds(p.parent, attempt, elapsed)
+	if !ok {
+		return 0, 0, false
+	}
+	if min < p.min {
+		min = p.min
+	}
+	if max < p.min {
+		max = p.min
+	}
+	return min, max, true
+}
+
+// WithScale returns a Policy that wraps the parent Policy and multiplies its backoffs
+// by the factor. It allows a shared base policy to back off more slowly or quickly
+// for some uses without duplicating its construction.
+func WithScale(parent Policy, factor float64) Policy {
+	return &scale{parent, factor}
+}
+
+type scale struct {
+	parent Policy
+	factor float64
+}
+
+func (p *scale) Next(err error, start, now time.Time, attempt int) (time.Duration, bool) {
+	return p.NextState(&State{Err: err, Start: start, Now: now, Attempt: attempt})
+}
+
+func (p *scale) NextState(s *State) (time.Duration, bool) {
+	d, ok := next(p.parent, s)
+	if !ok {
+		return 0, false
+	}
+	return time.Duration(float64(d) * p.factor), true
+}
+
+func (p *scale) String() string {
+	return fmt.Sprintf("WithScale(%v)", p.factor)
+}
+
+func (p *scale) spec() (Layer, Policy) {
+	return Layer{Type: "scale", Params: map[string]float64{"factor": p.factor}}, p.parent
+}
+
+func (p *scale) parents() []Policy { return []Policy{p.parent} }
+
+func (p *scale) bounds(attempt int, elapsed time.Duration) (time.Duration, time.Duration, bool) {
+	min, max, ok := bounds(p.parent, attempt, elapsed)
+	if !ok {
+		return 0, 0, false
+	}
+	return time.Duration(float64(min) * p.factor), time.Duration(float64(max) * p.factor), true
+}
+
+// WithMaxRetries returns a Policy that wraps the parent Policy and sets a limit
+// for the total number of retry attempts.
+func WithMaxRetries(parent Policy, limit int) Policy {
+	return &maxRetries{parent, limit}
+}
+
+type maxRetries struct {
+	parent Policy
+	limit  int
+}
+
+func (p *maxRetries) Next(err error, start, now time.Time, attempt int) (time.Duration, bool) {
+	return p.NextState(&State{Err: err, Start: start, Now: now, Attempt: attempt})
+}
+
+func (p *maxRetries) NextState(s *State) (time.Duration, bool) {
+	if s.Attempt > p.limit {
+		if p != never {
+			s.stop(LimitRetries)
+		}
+		return 0, false
+	}
+	return next(p.parent, s)
+}
+
+func (p *maxRetries) String() string {
+	return fmt.Sprintf("WithMaxRetries(%d)", p.limit)
+}
+
+func (p *maxRetries) spec() (Layer, Policy) {
+	if p == never {
+		return Layer{Type: "never"}, nil
+	}
+	return Layer{Type: "max_retries", Params: map[string]float64{"limit": float64(p.limit)}}, p.parent
+}
+
+func (p *maxRetries) parents() []Policy { return []Policy{p.parent} }
+
+func (p *maxRetries) bounds(attempt int, elapsed time.Duration) (time.Duration, time.Duration, bool) {
+	if attempt > p.limit {
+		return 0, 0, false
+	}
+	return bounds(p.parent, attempt, elapsed)
+}
+
+// WithMaxElapsedDuration returns a Policy that wraps the parent Policy and sets a limit
+// for the total elapsed duration in which retries are allowed.
+//
+// If the retry loop provides a deadline in its State, the earlier of the deadline
+// and the limit is enforced.
+func WithMaxElapsedDuration(parent Policy, limit time.Duration) Policy {
+	return &maxElapsed{parent, limit}
+}
+
+type maxElapsed struct {
+	parent Policy
+	limit  time.Duration
+}
+
+func (p *maxElapsed) Next(err error, start, now time.Time, attempt int) (time.Duration, bool) {
+	return p.NextState(&State{Err: err, Start: start, Now: now, Attempt: attempt})
+}
+
+func (p *maxElapsed) NextState(s *State) (time.Duration, bool) {
+	d, ok := next(p.parent, s)
+	if !ok {
+		return 0, false
+	}
+	if s.limit(s.Start.Add(p.limit)).Before(s.Now.Add(d)) {
+		s.stop(LimitElapsed)
+		return 0, false
+	}
+	return d, true
+}
+
+func (p *maxElapsed) String() string {
+	return fmt.Sprintf("WithMaxElapsedDuration(%v)", p.limit)
+}
+
+func (p *maxElapsed) spec() (Layer, Policy) {
+	return Layer{Type: "max_elapsed", Params: map[string]float64{"limit": p.limit.Seconds()}}, p.parent
+}
+
+func (p *maxElapsed) parents() []Policy { return []Policy{p.parent} }
+
+func (p *maxElapsed) limitsElapsed() {}
+
+func (p *maxElapsed) bounds(attempt int, elapsed time.Duration) (time.Duration, time.Duration, bool) {
+	return elapsedBounds(p.parent, p.limit, attempt, elapsed)
+}
+
+// WithMaxCumulativeBackoff returns a Policy that wraps the parent Policy and sets a limit
+// for the total duration spent backing off, independent of the time spent making attempts.
+// Unlike WithMaxElapsedDuration, slow attempts don't count against the limit.
+//
+// The total is kept in the retry loop's State. If the Policy isn't called as a StatePolicy,
+// only the current backoff counts against the limit.
+func WithMaxCumulativeBackoff(parent Policy, limit time.Duration) Policy {
+	return &maxCumulativeBackoff{parent, limit}
+}
+
+type maxCumulativeBackoff struct {
+	parent Policy
+	limit  time.Duration
+}
+
+func (p *maxCumulativeBackoff) Next(err error, start, now time.Time, attempt int) (time.Duration, bool) {
+	return p.NextState(&State{Err: err, Start: start, Now: now, Attempt: attempt})
+}
+
+func (p *maxCumulativeBackoff) NextState(s *State) (time.Duration, bool) {
+	d, ok := next(p.parent, s)
+	if !ok {
+		return 0, false
+	}
+	total, _ := s.Value(p).(time.Duration)
+	if total += d; total > p.limit {
+		return 0, false
+	}
+	s.SetValue(p, total)
+	return d, true
+}
+
+func (p *maxCumulativeBackoff) String() string {
+	return fmt.Sprintf("WithMaxCumulativeBackoff(%v)", p.limit)
+}
+
+func (p *maxCumulativeBackoff) spec() (Layer, Policy) {
+	return Layer{Type: "max_cumulative_backoff", Params: map[string]float64{"limit": p.limit.Seconds()}}, p.parent
+}
+
+func (p *maxCumulativeBackoff) parents() []Policy { return []Policy{p.parent} }
+
+func (p *maxCumulativeBackoff) bounds(attempt int, elapsed time.Duration) (time.Duration, time.Duration, bool) {
+	// The time already spent backing off isn't known, so only the next backoff counts.
+	return elapsedBounds(p.parent, p.limit, attempt, 0)
+}
+
+// WithAttemptsPerWindow returns a Policy that wraps the parent Policy and limits the
+// number of attempts within any rolling window to n, such as at most 10 attempts per
+// minute, independently of the total number of attempts. If the next attempt would
+// exceed the limit, its backoff is extended until the oldest attempt in the window expires.
+func WithAttemptsPerWindow(parent Policy, n int, window time.Duration) Policy {
+	return &attemptsPerWindow{parent: parent, n: max(n, 1), window: window}
+}
+
+type attemptsPerWindow struct {
+	parent Policy
+	n      int
+	window time.Duration
+}
+
+func (p *attemptsPerWindow) Next(err error, start, now time.Time, attempt int) (time.Duration, bool) {
+	return p.NextState(&State{Err: err, Start: start, Now: now, Attempt: attempt})
+}
+
+func (p *attemptsPerWindow) NextState(s *State) (time.Duration, bool) {
+	d, ok := next(p.parent, s)
+	if !ok {
+		return 0, false
+	}
+	// The times of the attempts in the window, oldest first.
+	// The first attempt is assumed to have been made at the start of the loop.
+	times, _ := s.Value(p).([]time.Time)
+	if times == nil {
+		times = []time.Time{s.Start}
+	}
+	t := s.Now.Add(d)
+	for len(times) > 0 && !times[0].After(t.Add(-p.window)) {
+		times = times[1:]
+	}
+	if len(times) >= p.n {
+		// Wait for enough of the oldest attempts to leave the window.
+		oldest := times[len(times)-p.n]
+		t = oldest.Add(p.window)
+		d = t.Sub(s.Now)
+		times = times[len(times)-p.n+1:]
+	}
+	s.SetValue(p, append(times, t))
+	return d, true
+}
+
+func (p *attemptsPerWindow) String() string {
+	return fmt.Sprintf("WithAttemptsPerWindow(%d, %v)", p.n, p.window)
+}
+
+func (p *attemptsPerWindow) spec() (Layer, Policy) {
+	return Layer{Type: "attempts_per_window", Params: map[string]float64{
+		"n":      float64(p.n),
+		"window": p.window.Seconds(),
+	}}, p.parent
+}
+
+func (p *attemptsPerWindow) parents() []Policy { return []Policy{p.parent} }
+
+func (p *attemptsPerWindow) bounds(attempt int, elapsed time.Duration) (time.Duration, time.Duration, bool) {
+	// The times of previous attempts aren't known, so the backoff may be extended
+	// by up to the window.
+	min, max, ok := bounds(p.parent, attempt, elapsed)
+	if !ok {
+		return 0, 0, false
+	}
+	if max < p.window {
+		max = p.window
+	}
+	return min, max, true
+}
+
+// elapsedBounds returns the bounds of the parent that fit within the remaining elapsed limit.
+func elapsedBounds(parent Policy, limit time.Duration, attempt int, elapsed time.Duration) (time.Duration, time.Duration, bool) {
+	min, max, ok := bounds(parent, attempt, elapsed)
+	remaining := limit - elapsed
+	if !ok || min > remaining {
+		return 0, 0, false
+	}
+	if max > remaining {
+		max = remaining
+	}
+	return min, max, true
+}
+
+// Limit identifies the limit that stopped a Policy from retrying.
+type Limit int
+
+// Limits that may stop a Policy from retrying.
+const (
+	// LimitNone indicates that no limit was reached.
+	LimitNone Limit = iota
+	// LimitParent indicates that the parent Policy of WithLimits stopped retrying
+	// without reaching a Limit.
+	LimitParent
+	// LimitRetries indicates that the limit for the total number of retry attempts was reached.
+	LimitRetries
+	// LimitElapsed indicates that the limit for the total elapsed duration was reached.
+	LimitElapsed
+)
+
+var limitNames = [...]string{
+	LimitNone:    "none",
+	LimitParent:  "parent",
+	LimitRetries: "max retries",
+	LimitElapsed: "max elapsed duration",
+}
+
+func (l Limit) String() string {
+	if l < 0 || int(l) >= len(limitNames) {
+		return "Limit(" + strconv.Itoa(int(l)) + ")"
+	}
+	return limitNames[l]
+}
+
+// WithLimits returns a Policy that wraps the parent Policy and sets limits for both
+// the total number of retry attempts and the total elapsed duration in which retries
+// are allowed. It's equivalent to combining WithMaxRetries and WithMaxElapsedDuration,
+// except that it also reports when its parent stopped retrying. When it stops a Retrier,
+// the limit is reported by the returned Error's Limit method.
+//
+// If either limit is negative, it isn't enforced. If the retry loop provides a deadline
+// in its State, the earlier of the deadline and the elapsed limit is enforced.
+func WithLimits(parent Policy, maxRetries int, maxElapsed time.Duration) Policy {
+	return &limits{parent: parent, retries: maxRetries, elapsed: maxElapsed}
+}
+
+type limits struct {
+	parent  Policy
+	retries int
+	elapsed time.Duration
+}
+
+func (p *limits) Next(err error, start, now time.Time, attempt int) (time.Duration, bool) {
+	return p.NextState(&State{Err: err, Start: start, Now: now, Attempt: attempt})
+}
+
+func (p *limits) NextState(s *State) (time.Duration, bool) {
+	if p.retries >= 0 && s.Attempt > p.retries {
+		s.stop(LimitRetries)
+		return 0, false
+	}
+	d, ok := next(p.parent, s)
+	if !ok {
+		s.stop(LimitParent)
+		return 0, false
+	}
+	if p.elapsed >= 0 && s.limit(s.Start.Add(p.elapsed)).Before(s.Now.Add(d)) {
+		s.stop(LimitElapsed)
+		return 0, false
+	}
+	return d, true
+}
+
+func (p *limits) String() string {
+	return fmt.Sprintf("WithLimits(%d, %v)", p.retries, p.elapsed)
+}
+
+func (p *limits) spec() (Layer, Policy) {
+	return Layer{Type: "limits", Params: map[string]float64{
+		"max_retries": float64(p.retries),
+		"max_elapsed": p.elapsed.Seconds(),
+	}}, p.parent
+}
+
+func (p *limits) parents() []Policy { return []Policy{p.parent} }
+
+func (p *limits) limitsElapsed() {}
+
+func (p *limits) bounds(attempt int, elapsed time.Duration) (time.Duration, time.Duration, bool) {
+	if p.retries >= 0 && attempt > p.retries {
+		return 0, 0, false
+	}
+	if p.elapsed < 0 {
+		return bounds(p.parent, attempt, elapsed)
+	}
+	return elapsedBounds(p.parent, p.elapsed, attempt, elapsed)
+}
+
+// WithMaxElapsedDurationPerClass returns a Policy that wraps the parent Policy and sets a limit
+// for the elapsed duration in which retries are allowed for each class of error. The elapsed
+// duration restarts whenever classify returns a different class than it did for the previous
+// error, since a fresh failure mode arguably deserves a fresh budget.
+//
+// The classes of previous errors are kept in the retry loop's State. If the Policy isn't
+// called as a StatePolicy, it behaves like WithMaxElapsedDuration.
+func WithMaxElapsedDurationPerClass(parent Policy, limit time.Duration, classify func(error) string) Policy {
+	return &maxElapsedPerClass{parent: parent, limit: limit, classify: classify}
+}
+
+type maxElapsedPerClass struct {
+	parent   Policy
+	limit    time.Duration
+	classify func(error) string
+}
+
+type errorClass struct {
+	name  string
+	since time.Time
+}
+
+func (p *maxElapsedPerClass) Next(err error, start, now time.Time, attempt int) (time.Duration, bool) {
+	return p.NextState(&State{Err: err, Start: start, Now: now, Attempt: attempt})
+}
+
+func (p *maxElapsedPerClass) NextState(s *State) (time.Duration, bool) {
+	name := p.classify(s.Err)
+	class, ok := s.Value(p).(errorClass)
+	switch {
+	case !ok:
+		class = errorClass{name: name, since: s.Start}
+	case class.name != name:
+		class = errorClass{name: name, since: s.Now}
+	}
+	s.SetValue(p, class)
+
+	d, ok := next(p.parent, s)
+	if s.limit(class.since.Add(p.limit)).Before(s.Now.Add(d)) {
+		return 0, false
+	}
+	return d, ok
+}
+
+func (p *maxElapsedPerClass) String() string {
+	return fmt.Sprintf("WithMaxElapsedDurationPerClass(%v)", p.limit)
+}
+
+func (p *maxElapsedPerClass) parents() []Policy { return []Policy{p.parent} }
+
+func (p *maxElapsedPerClass) limitsElapsed() {}
+
+func (p *maxElapsedPerClass) bounds(attempt int, elapsed time.Duration) (time.Duration, time.Duration, bool) {
+	return elapsedBounds(p.parent, p.limit, attempt, elapsed)
+}
+
+// WithFirstRetryWithin returns a Policy that wraps the parent Policy and guarantees
+// that the first retry attempt happens within the limit by clamping its backoff.
+// Subsequent backoffs aren't affected. It's useful for interactive flows in which the
+// first recovery attempt must be fast but later backoffs can grow normally.
+func WithFirstRetryWithin(parent Policy, limit time.Duration) Policy {
+	return &firstRetryWithin{parent: parent, limit: limit}
+}
+
+type firstRetryWithin struct {
+	parent Policy
+	limit  time.Duration
+}
+
+func (p *firstRetryWithin) Next(err error, start, now time.Time, attempt int) (time.Duration, bool) {
+	return p.NextState(&State{Err: err, Start: start, Now: now, Attempt: attempt})
+}
+
+func (p *firstRetryWithin) NextState(s *State) (time.Duration, bool) {
+	d, ok := next(p.parent, s)
+	if s.Attempt == 1 {
+		d = min(d, p.limit)
+	}
+	return d, ok
+}
+
+func (p *firstRetryWithin) String() string {
+	return fmt.Sprintf("WithFirstRetryWithin(%v)", p.limit)
+}
+
+func (p *firstRetryWithin) parents() []Policy { return []Policy{p.parent} }
+
+func (p *firstRetryWithin) spec() (Layer, Policy) {
+	return Layer{Type: "first_retry_within", Params: map[string]float64{"limit": p.limit.Seconds()}}, p.parent
+}
+
+func (p *firstRetryWithin) bounds(attempt int, elapsed time.Duration) (time.Duration, time.Duration, bool) {
+	lo, hi, ok := bounds(p.parent, attempt, elapsed)
+	if attempt == 1 {
+		lo, hi = min(lo, p.limit), min(hi, p.limit)
+	}
+	return lo, hi, ok
+}
+
+// WithAttemptSkip returns a Policy that wraps the parent Policy and skips ahead to the
+// attempt returned by skipTo, if it's after the current attempt, before consulting the parent.
+// For example, it can jump to the max backoff immediately after a severe throttling error.
+//
+// Skipped attempts persist for the rest of the retry loop if the Policy is called as a
+// StatePolicy; otherwise they only affect the current decision.
+func WithAttemptSkip(parent Policy, skipTo func(err error, attempt int) int) Policy {
+	return &attemptSkip{parent: parent, skipTo: skipTo}
+}
+
+type attemptSkip struct {
+	parent Policy
+	skipTo func(error, int) int
+}
+
+func (p *attemptSkip) Next(err error, start, now time.Time, attempt int) (time.Duration, bool) {
+	return p.NextState(&State{Err: err, Start: start, Now: now, Attempt: attempt})
+}
+
+func (p *attemptSkip) NextState(s *State) (time.Duration, bool) {
+	s.SkipTo(p.skipTo(s.Err, s.Attempt))
+	return next(p.parent, s)
+}
+
+func (p *attemptSkip) String() string {
+	return "WithAttemptSkip"
+}
+
+func (p *attemptSkip) parents() []Policy { return []Policy{p.parent} }
+
+// WithJitterSource returns a Policy that wraps the parent Policy and provides src as the
+// source of randomness for the jitter layers that it wraps, instead of the global source.
+// For example, a seeded source makes jitter reproducible in tests and separate sources
+// isolate tenants from each other.
+//
+// The source is guarded by a mutex, so it doesn't need to be safe for concurrent use.
+func WithJitterSource(parent Policy, src rand.Source) Policy {
+	return &jitterSource{parent: parent, rand: &lockedRand{r: rand.New(src)}}
+}
+
+// NewJitterSource returns a source of randomness for WithJitterSource that's seeded from
+// a stable identity, such as a hostname or pod name, and a boot counter that's incremented
+// each time the process starts. Unlike seeding from the time, it guarantees that a crash
+// looping process picks a different jitter sequence after each restart, and that processes
+// with different identities don't pick the same sequence, against a struggling dependency.
+func NewJitterSource(identity string, boot uint64) rand.Source {
+	h := sha256.New()
+	h.Write([]byte(identity))
+	h.Write(binary.BigEndian.AppendUint64(nil, boot))
+	sum := h.Sum(nil)
+	return rand.NewPCG(binary.BigEndian.Uint64(sum[:8]), binary.BigEndian.Uint64(sum[8:16]))
+}
+
+type jitterSource struct {
+	parent Policy
+	rand   *lockedRand
+}
+
+func (p *jitterSource) Next(err error, start, now time.Time, attempt int) (time.Duration, bool) {
+	return p.NextState(&State{Err: err, Start: start, Now: now, Attempt: attempt})
+}
+
+func (p *jitterSource) NextState(s *State) (time.Duration, bool) {
+	prev := s.rand
+	s.rand = p.rand
+	defer func() { s.rand = prev }()
+	return next(p.parent, s)
+}
+
+func (p *jitterSource) String() string {
+	return "WithJitterSource"
+}
+
+func (p *jitterSource) parents() []Policy { return []Policy{p.parent} }
+
+func (p *jitterSource) bounds(attempt int, elapsed time.Duration) (time.Duration, time.Duration, bool) {
+	return bounds(p.parent, attempt, elapsed)
+}
+
+// WithExactFirstBackoff returns a Policy that wraps the parent Policy and exempts the
+// first retry attempt's backoff from the jitter layers that it wraps, while later backoffs
+// are jittered. It's for clients that must retry after exactly the documented backoff the
+// first time but still want desynchronization afterwards.
+func WithExactFirstBackoff(parent Policy) Policy {
+	return &exactFirstBackoff{parent}
+}
+
+type exactFirstBackoff struct {
+	parent Policy
+}
+
+func (p *exactFirstBackoff) Next(err error, start, now time.Time, attempt int) (time.Duration, bool) {
+	return p.NextState(&State{Err: err, Start: start, Now: now, Attempt: attempt})
+}
+
+func (p *exactFirstBackoff) NextState(s *State) (time.Duration, bool) {
+	if s.Attempt != 1 {
+		return next(p.parent, s)
+	}
+	prev := s.exact
+	s.exact = true
+	defer func() { s.exact = prev }()
+	return next(p.parent, s)
+}
+
+func (p *exactFirstBackoff) String() string {
+	return "WithExactFirstBackoff"
+}
+
+func (p *exactFirstBackoff) spec() (Layer, Policy) {
+	return Layer{Type: "exact_first_backoff"}, p.parent
+}
+
+func (p *exactFirstBackoff) parents() []Policy { return []Policy{p.parent} }
+
+func (p *exactFirstBackoff) bounds(attempt int, elapsed time.Duration) (time.Duration, time.Duration, bool) {
+	return bounds(p.parent, attempt, elapsed)
+}
+
+// WithMonotoneBackoff returns a Policy that wraps the parent Policy and guarantees that
+// each backoff is at least as long as the previous one, even after jitter, for protocols
+// and auditors that require non-decreasing retry intervals.
+//
+// The previous backoff is kept in the retry loop's State. If the Policy isn't called as
+// a StatePolicy, it can't guarantee anything and it returns the parent's backoffs.
+func WithMonotoneBackoff(parent Policy) Policy {
+	return &monotoneBackoff{parent}
+}
+
+type monotoneBackoff struct {
+	parent Policy
+}
+
+func (p *monotoneBackoff) Next(err error, start, now time.Time, attempt int) (time.Duration, bool) {
+	return p.NextState(&State{Err: err, Start: start, Now: now, Attempt: attempt})
+}
+
+func (p *monotoneBackoff) NextState(s *State) (time.Duration, bool) {
+	d, ok := next(p.parent, s)
+	if !ok {
+		return 0, false
+	}
+	if prev, ok := s.Value(p).(time.Duration); ok && d < prev {
+		d = prev
+	}
+	s.SetValue(p, d)
+	return d, true
+}
+
+func (p *monotoneBackoff) String() string {
+	return "WithMonotoneBackoff"
+}
+
+func (p *monotoneBackoff) spec() (Layer, Policy) {
+	return Layer{Type: "monotone"}, p.parent
+}
+
+func (p *monotoneBackoff) parents() []Policy { return []Policy{p.parent} }
+
+func (p *monotoneBackoff) bounds(attempt int, elapsed time.Duration) (time.Duration, time.Duration, bool) {
+	var lo, hi time.Duration
+	for i := 1; i <= attempt; i++ {
+		min, max, ok := bounds(p.parent, i, elapsed)
+		if !ok {
+			if i == attempt {
+				return 0, 0, false
+			}
+			continue
+		}
+		if min > lo {
+			lo = min
+		}
+		if max > hi {
+			hi = max
+		}
+	}
+	return lo, hi, true
+}
+
+// A TruncationOption configures WithDeadlineTruncation.
+type TruncationOption interface {
+	apply(*deadlineTruncation)
+}
+
+type truncationOptionFunc func(*deadlineTruncation)
+
+func (fn truncationOptionFunc) apply(p *deadlineTruncation) { fn(p) }
+
+// WithTruncationMargin returns a TruncationOption that caps backoffs so that the next
+// attempt starts the margin before the retry loop's deadline. The margin must cover
+// the time the retry loop takes to schedule the attempt, as well as the attempt itself,
+// or else the retry loop gives up because the next attempt would exceed the deadline.
+// Negative margins are treated as zero, which is the default.
+func WithTruncationMargin(margin time.Duration) TruncationOption {
+	return truncationOptionFunc(func(p *deadlineTruncation) {
+		p.margin = max(margin, 0)
+	})
+}
+
+// WithDeadlineTruncation returns a Policy that wraps the parent Policy and caps each
+// backoff to the time remaining before the retry loop's deadline, so that a long backoff
+// doesn't waste a request that still has some of its budget left. If no time remains,
+// it stops retrying.
+//
+// The deadline is the context's deadline, which a Retrier provides to the policy as
+// State.Deadline. A Retrier whose policy includes WithDeadlineTruncation provides it
+// as if it were configured with WithPolicyDeadline. Other retry loops, such as those
+// of Attempts and Repeat, don't provide a deadline, so it returns the parent's backoffs.
+func WithDeadlineTruncation(parent Policy, opts ...TruncationOption) Policy {
+	p := &deadlineTruncation{parent: parent}
+	for _, o := range opts {
+		o.apply(p)
+	}
+	return p
+}
+
+type deadlineTruncation struct {
+	parent Policy
+	margin time.Duration
+}
+
+func (p *deadlineTruncation) Next(err error, start, now time.Time, attempt int) (time.Duration, bool) {
+	return p.NextState(&State{Err: err, Start: start, Now: now, Attempt: attempt})
+}
+
+func (p *deadlineTruncation) NextState(s *State) (time.Duration, bool) {
+	d, ok := next(p.parent, s)
+	if !ok || s.Deadline.IsZero() {
+		return d, ok
+	}
+	remaining := s.Deadline.Sub(s.Now) - p.margin
+	if remaining <= 0 {
+		return 0, false
+	}
+	return min(d, remaining), true
+}
+
+func (p *deadlineTruncation) String() string {
+	return fmt.Sprintf("WithDeadlineTruncation(%v)", p.margin)
+}
+
+func (p *deadlineTruncation) spec() (Layer, Policy) {
+	return Layer{Type: "deadline_truncation", Params: map[string]float64{"margin": p.margin.Seconds()}}, p.parent
+}
+
+func (p *deadlineTruncation) parents() []Policy { return []Policy{p.parent} }
+
+func (p *deadlineTruncation) usesDeadline() {}
+
+func (p *deadlineTruncation) bounds(attempt int, elapsed time.Duration) (time.Duration, time.Duration, bool) {
+	return bounds(p.parent, attempt, elapsed)
+}
+
+type lockedRand struct {
+	mu sync.Mutex
+	r  *rand.Rand
+}
+
+func (r *lockedRand) Float64() float64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.r.Float64()
+}
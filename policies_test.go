@@ -0,0 +1,265 @@
+// SPDX-License-Identifier: MIT
+//
+// Copyright 2022 Andrew Bursavich. All rights reserved.
+// Use of this source code is governed by The MIT License
+// which can be found in the LICENSE file.
+
+package retry_test
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"bursavich.dev/retry"
+	"bursavich.dev/retry/retrytest"
+)
+
+var errTest = errors.New("test error")
+
+func TestDeadlineTruncation(t *testing.T) {
+	tests := []struct {
+		name     string
+		clock    func(time.Time) retry.Clock
+		deadline time.Duration
+		margin   time.Duration
+		attempts int
+		explicit bool // configured with WithPolicyDeadline
+	}{
+		{
+			name:     "fake clock",
+			clock:    func(now time.Time) retry.Clock { return retrytest.NewAutoClock(now) },
+			deadline: 10 * time.Second,
+			margin:   time.Second,
+			attempts: 2,
+			explicit: true,
+		},
+		{
+			name:     "real clock",
+			clock:    func(time.Time) retry.Clock { return retry.SystemClock() },
+			deadline: 300 * time.Millisecond,
+			margin:   100 * time.Millisecond,
+			attempts: 2,
+			explicit: true,
+		},
+		{
+			name:     "implied policy deadline",
+			clock:    func(now time.Time) retry.Clock { return retrytest.NewAutoClock(now) },
+			deadline: 10 * time.Second,
+			margin:   time.Second,
+			attempts: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Now()
+			clock := tt.clock(now)
+			ctx, cancel := context.WithDeadline(context.Background(), now.Add(tt.deadline))
+			defer cancel()
+
+			policy := retry.WithDeadlineTruncation(retry.ConstantBackoff(time.Hour), retry.WithTruncationMargin(tt.margin))
+			opts := []retry.Option{retry.WithClock(clock)}
+			if tt.explicit {
+				opts = append(opts, retry.WithPolicyDeadline())
+			}
+			r := retry.New(retry.WithMaxRetries(policy, 10), opts...)
+			var starts []time.Time
+			err := r.Do(ctx, func() error {
+				starts = append(starts, clock.Now())
+				return errTest
+			})
+
+			if len(starts) != tt.attempts {
+				t.Fatalf("attempts: got %d; want %d", len(starts), tt.attempts)
+			}
+			var rerr *retry.Error
+			if !errors.As(err, &rerr) {
+				t.Fatalf("error: got %v; want *retry.Error", err)
+			}
+			if got, want := rerr.Reason(), retry.ReasonPolicy; got != want {
+				t.Errorf("reason: got %v; want %v", got, want)
+			}
+			// The truncated attempt starts the margin before the deadline.
+			if got, want := starts[1].Sub(now), tt.deadline-tt.margin; got < want {
+				t.Errorf("truncated attempt: got %v after start; want at least %v", got, want)
+			}
+		})
+	}
+}
+
+func TestForever(t *testing.T) {
+	tests := []struct {
+		name     string
+		min, max time.Duration
+		wantMax  time.Duration
+	}{
+		{name: "capped", min: time.Second, max: 10 * time.Second, wantMax: 10 * time.Second},
+		{name: "defaults", wantMax: retry.DefaultMaxBackoff},
+		{name: "max below min", min: time.Second, max: time.Millisecond, wantMax: time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := retry.Forever(tt.min, tt.max).(retry.StatePolicy)
+			start := time.Now()
+			s := &retry.State{Start: start, Now: start}
+			var prev time.Duration
+			grew := false
+			for i := 1; i <= 100; i++ {
+				s.Attempt = i
+				d, ok := p.NextState(s)
+				if !ok {
+					t.Fatalf("attempt %d: stopped retrying", i)
+				}
+				if d < 0 || d > tt.wantMax {
+					t.Fatalf("attempt %d: backoff %v is outside of [0, %v]", i, d, tt.wantMax)
+				}
+				grew = grew || d > prev
+				prev = d
+				s.Now = s.Now.Add(d)
+			}
+			if !grew {
+				t.Error("backoff never grew")
+			}
+		})
+	}
+}
+
+func TestForeverResetsWhenStable(t *testing.T) {
+	p := retry.Forever(time.Second, 10*time.Second).(retry.StatePolicy)
+	start := time.Now()
+	s := &retry.State{Start: start, Now: start}
+	for i := 1; i <= 10; i++ {
+		s.Attempt = i
+		d, _ := p.NextState(s)
+		s.Now = s.Now.Add(d)
+	}
+	// The next attempt lasts longer than max.
+	s.Now = s.Now.Add(time.Minute)
+	s.Attempt++
+	if d, _ := p.NextState(s); d > 1500*time.Millisecond {
+		t.Errorf("backoff after a stable attempt: got %v; want at most 1.5s", d)
+	}
+}
+
+func TestLinearBackoffDefaults(t *testing.T) {
+	tests := []struct {
+		name              string
+		start, step, max  time.Duration
+		first, second, at time.Duration // backoffs of attempts 1, 2, and 1000
+	}{
+		{
+			name:  "explicit",
+			start: time.Second, step: 2 * time.Second, max: time.Minute,
+			first: time.Second, second: 3 * time.Second, at: time.Minute,
+		},
+		{
+			name:  "defaults",
+			first: retry.DefaultMinBackoff, second: 2 * retry.DefaultMinBackoff, at: retry.DefaultMaxBackoff,
+		},
+		{
+			name:  "negative",
+			start: -time.Second, step: -time.Second, max: -time.Second,
+			first: retry.DefaultMinBackoff, second: 2 * retry.DefaultMinBackoff, at: retry.DefaultMaxBackoff,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := retry.LinearBackoff(tt.start, tt.step, tt.max)
+			for attempt, want := range map[int]time.Duration{1: tt.first, 2: tt.second, 1000: tt.at} {
+				if got, _ := p.Next(errTest, time.Time{}, time.Time{}, attempt); got != want {
+					t.Errorf("attempt %d: got %v; want %v", attempt, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestPolynomialBackoffDefaults(t *testing.T) {
+	tests := []struct {
+		name              string
+		base, max         time.Duration
+		exponent          float64
+		first, second, at time.Duration // backoffs of attempts 1, 2, and 1000
+	}{
+		{
+			name: "explicit",
+			base: time.Second, exponent: 3, max: time.Minute,
+			first: time.Second, second: 8 * time.Second, at: time.Minute,
+		},
+		{
+			name:  "defaults",
+			first: retry.DefaultMinBackoff, second: 4 * retry.DefaultMinBackoff, at: retry.DefaultMaxBackoff,
+		},
+		{
+			name: "negative",
+			base: -time.Second, exponent: -1, max: -time.Second,
+			first: retry.DefaultMinBackoff, second: 4 * retry.DefaultMinBackoff, at: retry.DefaultMaxBackoff,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := retry.PolynomialBackoff(tt.base, tt.exponent, tt.max)
+			for attempt, want := range map[int]time.Duration{1: tt.first, 2: tt.second, 1000: tt.at} {
+				if got, _ := p.Next(errTest, time.Time{}, time.Time{}, attempt); got != want {
+					t.Errorf("attempt %d: got %v; want %v", attempt, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestProportionalBackoffDefaults(t *testing.T) {
+	tests := []struct {
+		name              string
+		fraction          float64
+		min, max          time.Duration
+		short, long, none time.Duration // backoffs with 10s remaining, 1000s remaining, and no deadline
+	}{
+		{
+			name:     "explicit",
+			fraction: 0.2, min: time.Second, max: 10 * time.Second,
+			short: 2 * time.Second, long: 10 * time.Second, none: 10 * time.Second,
+		},
+		{
+			name:  "defaults",
+			short: time.Second, long: retry.DefaultMaxBackoff, none: retry.DefaultMaxBackoff,
+		},
+		{
+			name:     "invalid",
+			fraction: math.NaN(), min: -time.Second, max: -time.Second,
+			short: time.Second, long: retry.DefaultMaxBackoff, none: retry.DefaultMaxBackoff,
+		},
+		{
+			name:     "negative fraction",
+			fraction: -1, max: time.Hour,
+			short: time.Second, long: 100 * time.Second, none: time.Hour,
+		},
+		{
+			name:     "fraction over one",
+			fraction: 2, max: time.Hour,
+			short: 10 * time.Second, long: 1000 * time.Second, none: time.Hour,
+		},
+		{
+			name:     "max less than min",
+			fraction: 0.1, min: 5 * time.Second, max: time.Second,
+			short: 5 * time.Second, long: 5 * time.Second, none: 5 * time.Second,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := retry.ProportionalBackoff(tt.fraction, tt.min, tt.max).(retry.StatePolicy)
+			now := time.Now()
+			for remaining, want := range map[time.Duration]time.Duration{10 * time.Second: tt.short, 1000 * time.Second: tt.long, 0: tt.none} {
+				s := &retry.State{Err: errTest, Start: now, Now: now, Attempt: 1}
+				if remaining > 0 {
+					s.Deadline = now.Add(remaining)
+				}
+				if got, ok := p.NextState(s); !ok || got != want {
+					t.Errorf("remaining %v: got (%v, %v); want (%v, true)", remaining, got, ok, want)
+				}
+			}
+		})
+	}
+}
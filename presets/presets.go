@@ -0,0 +1,82 @@
+// SPDX-License-Identifier: MIT
+//
+// Copyright 2022 Andrew Bursavich. All rights reserved.
+// Use of this source code is governed by The MIT License
+// which can be found in the LICENSE file.
+
+// Package presets provides curated retry policies for common kinds of dependencies.
+//
+// Presets are versioned. A preset's behavior never changes once it's published;
+// changes are published as a new version so that upgrading is a deliberate choice.
+package presets
+
+import (
+	"time"
+
+	"bursavich.dev/retry"
+)
+
+var objectStorageV1 = retry.WithLimits(
+	retry.WithDefaultRandomJitter(retry.ExponentialBackoff(500*time.Millisecond, 30*time.Second, 2)),
+	-1, 2*time.Minute,
+)
+
+// ObjectStorageV1 returns a Policy for object storage requests.
+//
+// Object storage services shed load with 429 and 503 responses and recommend
+// truncated exponential backoff starting within a second. Requests are usually
+// idempotent and not latency sensitive, so retries continue for up to 2 minutes.
+//
+// It starts at 500ms, doubles up to 30s, and uses the default jitter.
+func ObjectStorageV1() retry.Policy {
+	return objectStorageV1
+}
+
+var databaseConnectV1 = retry.WithLimits(
+	retry.WithDefaultRandomJitter(retry.ExponentialBackoff(250*time.Millisecond, 10*time.Second, 2)),
+	-1, time.Minute,
+)
+
+// DatabaseConnectV1 returns a Policy for establishing relational database connections.
+//
+// Connection failures are commonly caused by failovers and restarts which take
+// seconds to tens of seconds. Connection storms make recovery slower, so backoff
+// grows quickly and is jittered. Retries continue for up to a minute, after which
+// the failure is likely to require intervention.
+//
+// It starts at 250ms, doubles up to 10s, and uses the default jitter.
+func DatabaseConnectV1() retry.Policy {
+	return databaseConnectV1
+}
+
+var oauthTokenV1 = retry.WithLimits(
+	retry.WithDefaultRandomJitter(retry.ExponentialBackoff(200*time.Millisecond, 5*time.Second, 2)),
+	5, 30*time.Second,
+)
+
+// OAuthTokenV1 returns a Policy for requesting tokens from OAuth token endpoints.
+//
+// Callers are usually blocked until a token is acquired and token endpoints are
+// aggressively rate limited, so it makes a few quick retries and gives up within
+// 30 seconds rather than amplifying an outage of a shared identity provider.
+//
+// It starts at 200ms, doubles up to 5s, uses the default jitter, and allows 5 retries.
+func OAuthTokenV1() retry.Policy {
+	return oauthTokenV1
+}
+
+var rateLimitedRESTV1 = retry.WithLimits(
+	retry.WithDefaultRandomJitter(retry.ExponentialBackoff(time.Second, time.Minute, 2)),
+	10, 5*time.Minute,
+)
+
+// RateLimitedRESTV1 returns a Policy for REST APIs that frequently respond with 429 Too Many Requests.
+//
+// Rate limits are commonly enforced per minute, so backoff starts at a second and
+// grows to a minute to let the limit window reset. Jitter spreads out clients that
+// were limited together. It allows 10 retries within 5 minutes.
+//
+// It starts at 1s, doubles up to 1m, and uses the default jitter.
+func RateLimitedRESTV1() retry.Policy {
+	return rateLimitedRESTV1
+}
@@ -0,0 +1,60 @@
+// SPDX-License-Identifier: MIT
+//
+// Copyright 2022 Andrew Bursavich. All rights reserved.
+// Use of this source code is governed by The MIT License
+// which can be found in the LICENSE file.
+
+package presets_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"bursavich.dev/retry"
+	"bursavich.dev/retry/presets"
+)
+
+// TestPresets pins the published presets, whose behavior must never change.
+func TestPresets(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy retry.Policy
+		spec   string
+	}{
+		{
+			name:   "ObjectStorageV1",
+			policy: presets.ObjectStorageV1(),
+			spec:   `{"version":1,"type":"exponential","params":{"factor":2,"max":30,"min":0.5},"wrappers":[{"type":"random_jitter","params":{"factor":0.5}},{"type":"limits","params":{"max_elapsed":120,"max_retries":-1}}]}`,
+		},
+		{
+			name:   "DatabaseConnectV1",
+			policy: presets.DatabaseConnectV1(),
+			spec:   `{"version":1,"type":"exponential","params":{"factor":2,"max":10,"min":0.25},"wrappers":[{"type":"random_jitter","params":{"factor":0.5}},{"type":"limits","params":{"max_elapsed":60,"max_retries":-1}}]}`,
+		},
+		{
+			name:   "OAuthTokenV1",
+			policy: presets.OAuthTokenV1(),
+			spec:   `{"version":1,"type":"exponential","params":{"factor":2,"max":5,"min":0.2},"wrappers":[{"type":"random_jitter","params":{"factor":0.5}},{"type":"limits","params":{"max_elapsed":30,"max_retries":5}}]}`,
+		},
+		{
+			name:   "RateLimitedRESTV1",
+			policy: presets.RateLimitedRESTV1(),
+			spec:   `{"version":1,"type":"exponential","params":{"factor":2,"max":60,"min":1},"wrappers":[{"type":"random_jitter","params":{"factor":0.5}},{"type":"limits","params":{"max_elapsed":300,"max_retries":10}}]}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec, err := retry.SpecOf(tt.policy)
+			if err != nil {
+				t.Fatalf("SpecOf: %v", err)
+			}
+			b, err := json.Marshal(spec)
+			if err != nil {
+				t.Fatalf("Marshal: %v", err)
+			}
+			if got := string(b); got != tt.spec {
+				t.Errorf("spec: got %s; want %s", got, tt.spec)
+			}
+		})
+	}
+}
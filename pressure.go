@@ -0,0 +1,260 @@
+// SPDX-License-Identifier: MIT
+//
+// Copyright 2022 Andrew Bursavich. All rights reserved.
+// Use of this source code is governed by The MIT License
+// which can be found in the LICENSE file.
+
+package retry
+
+import (
+	"context"
+	"math"
+	"runtime/metrics"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// PressureSnapshot is an aggregate snapshot of retry pressure.
+type PressureSnapshot struct {
+	// Time is the time at which the snapshot was taken.
+	Time time.Time
+	// Interval is the duration covered by the snapshot's counters.
+	Interval time.Duration
+	// Active is the number of loops that are currently running.
+	Active int
+	// Sleeping is the number of loops that are currently backing off.
+	Sleeping int
+	// Retries is the number of retries scheduled during the interval.
+	Retries int
+	// GiveUps is the number of loops that gave up during the interval, for any reason.
+	GiveUps int
+	// Reasons is the number of loops that gave up during the interval by the reason
+	// they stopped. It's nil if none gave up.
+	Reasons map[Reason]int
+}
+
+// RetryRate returns the number of retries per second during the interval.
+func (s PressureSnapshot) RetryRate() float64 {
+	return perSecond(s.Retries, s.Interval)
+}
+
+// GiveUpRate returns the number of give-ups per second during the interval.
+func (s PressureSnapshot) GiveUpRate() float64 {
+	return perSecond(s.GiveUps, s.Interval)
+}
+
+func perSecond(n int, d time.Duration) float64 {
+	if d <= 0 {
+		return 0
+	}
+	return float64(n) / d.Seconds()
+}
+
+// defaultPressureInterval is the default interval of a PressureReporter.
+const defaultPressureInterval = 10 * time.Second
+
+// PressureReporter periodically reports aggregate retry pressure of the Retriers it observes.
+// It's intended for feeding autoscaling or load-shedding controllers.
+type PressureReporter struct {
+	report func(PressureSnapshot)
+	ticker *time.Ticker
+	stop   chan struct{}
+	done   chan struct{}
+
+	mu       sync.Mutex
+	active   int
+	sleeping int
+	retries  int
+	reasons  map[Reason]int
+	last     time.Time
+}
+
+// NewPressureReporter returns a new PressureReporter that calls report with
+// a snapshot every interval until it's stopped. If interval isn't positive,
+// it defaults to 10s.
+func NewPressureReporter(interval time.Duration, report func(PressureSnapshot)) *PressureReporter {
+	if interval <= 0 {
+		interval = defaultPressureInterval
+	}
+	r := &PressureReporter{
+		report: report,
+		ticker: time.NewTicker(interval),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+		last:   time.Now(),
+	}
+	go r.run()
+	return r
+}
+
+// Option returns an Option that makes a Retrier report its retry loops to the PressureReporter.
+func (r *PressureReporter) Option() Option {
+	return WithObserver(pressureObserver{r})
+}
+
+// Stop stops reporting snapshots. No more reports will be made after it returns.
+func (r *PressureReporter) Stop() {
+	select {
+	case <-r.stop:
+	default:
+		close(r.stop)
+		r.ticker.Stop()
+	}
+	<-r.done
+}
+
+func (r *PressureReporter) run() {
+	defer close(r.done)
+	for {
+		select {
+		case <-r.stop:
+			return
+		case now := <-r.ticker.C:
+			r.report(r.snapshot(now))
+		}
+	}
+}
+
+func (r *PressureReporter) snapshot(now time.Time) PressureSnapshot {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s := PressureSnapshot{
+		Time:     now,
+		Interval: now.Sub(r.last),
+		Active:   r.active,
+		Sleeping: r.sleeping,
+		Retries:  r.retries,
+		Reasons:  r.reasons,
+	}
+	for _, n := range r.reasons {
+		s.GiveUps += n
+	}
+	r.retries, r.reasons, r.last = 0, nil, now
+	return s
+}
+
+// pressureObserver observes retry loops for a PressureReporter.
+type pressureObserver struct {
+	r *PressureReporter
+}
+
+func (o pressureObserver) AttemptStart(ctx context.Context, a Attempt) {
+	o.r.mu.Lock()
+	defer o.r.mu.Unlock()
+	if a.Number == 1 {
+		o.r.active++
+	} else {
+		o.r.sleeping-- // Woke from backing off.
+	}
+}
+
+func (o pressureObserver) AttemptEnd(ctx context.Context, a Attempt, err error) {}
+
+func (o pressureObserver) Backoff(ctx context.Context, a Attempt, err error, d time.Duration) {
+	o.r.mu.Lock()
+	defer o.r.mu.Unlock()
+	o.r.retries++
+	o.r.sleeping++
+}
+
+func (o pressureObserver) GiveUp(ctx context.Context, a Attempt, reason Reason, err error) {}
+
+func (o pressureObserver) finish(ctx context.Context, a Attempt, reason Reason, err error) {
+	if a.Number == 0 {
+		return // Stopped during the initial delay, before it was active.
+	}
+	o.r.mu.Lock()
+	defer o.r.mu.Unlock()
+	o.r.active--
+	if reason == ReasonContextDone {
+		o.r.sleeping-- // Stopped while backing off.
+	}
+	if reason != ReasonNone {
+		if o.r.reasons == nil {
+			o.r.reasons = make(map[Reason]int)
+		}
+		o.r.reasons[reason]++
+	}
+}
+
+// Pressure is a level of resource pressure on the process, such as memory or CPU pressure.
+type Pressure int
+
+// Levels of resource pressure.
+const (
+	// PressureNormal indicates that the process isn't under pressure.
+	PressureNormal Pressure = iota
+	// PressureHigh indicates that the process is under pressure.
+	PressureHigh
+)
+
+var pressureNames = [...]string{
+	PressureNormal: "normal",
+	PressureHigh:   "high",
+}
+
+func (p Pressure) String() string {
+	if p < 0 || int(p) >= len(pressureNames) {
+		return "Pressure(" + strconv.Itoa(int(p)) + ")"
+	}
+	return pressureNames[p]
+}
+
+// WithPressureGate returns a Policy that wraps the parent Policy and stops retrying
+// without sleeping while the pressure function reports high pressure, so that retries
+// become fast failures. Goroutines that sleep while holding large request state make
+// pressure worse during incidents. It behaves like the parent while pressure is normal.
+func WithPressureGate(parent Policy, pressure func() Pressure) Policy {
+	return &pressureGate{parent: parent, pressure: pressure}
+}
+
+type pressureGate struct {
+	parent   Policy
+	pressure func() Pressure
+}
+
+func (p *pressureGate) Next(err error, start, now time.Time, attempt int) (time.Duration, bool) {
+	return p.NextState(&State{Err: err, Start: start, Now: now, Attempt: attempt})
+}
+
+func (p *pressureGate) NextState(s *State) (time.Duration, bool) {
+	if p.pressure() >= PressureHigh {
+		return 0, false
+	}
+	return next(p.parent, s)
+}
+
+func (p *pressureGate) String() string {
+	return "WithPressureGate"
+}
+
+func (p *pressureGate) parents() []Policy { return []Policy{p.parent} }
+
+func (p *pressureGate) bounds(attempt int, elapsed time.Duration) (time.Duration, time.Duration, bool) {
+	return bounds(p.parent, attempt, elapsed)
+}
+
+// MemoryPressure returns a pressure function for WithPressureGate that reports high
+// pressure when the memory used by the Go runtime is at least the given fraction of
+// its soft memory limit, which is set by GOMEMLIMIT or debug.SetMemoryLimit.
+// It always reports normal pressure if there is no limit.
+func MemoryPressure(fraction float64) func() Pressure {
+	return func() Pressure {
+		samples := []metrics.Sample{
+			{Name: "/gc/gomemlimit:bytes"},
+			{Name: "/memory/classes/total:bytes"},
+			{Name: "/memory/classes/heap/released:bytes"},
+		}
+		metrics.Read(samples)
+		limit := sampleUint64(samples[0])
+		if limit == 0 || limit >= math.MaxInt64 {
+			return PressureNormal
+		}
+		used := sampleUint64(samples[1]) - sampleUint64(samples[2])
+		if float64(used) >= fraction*float64(limit) {
+			return PressureHigh
+		}
+		return PressureNormal
+	}
+}
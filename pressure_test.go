@@ -0,0 +1,75 @@
+// SPDX-License-Identifier: MIT
+//
+// Copyright 2022 Andrew Bursavich. All rights reserved.
+// Use of this source code is governed by The MIT License
+// which can be found in the LICENSE file.
+
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPressureReporter(t *testing.T) {
+	pr := NewPressureReporter(time.Hour, func(PressureSnapshot) {})
+	defer pr.Stop()
+	errTest := errors.New("test error")
+
+	// A loop that's backing off.
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- New(ConstantBackoff(time.Hour), pr.Option()).Do(ctx, func() error { return errTest })
+	}()
+	for {
+		if s := pr.snapshot(time.Now()); s.Sleeping == 1 {
+			if s.Active != 1 || s.Retries != 1 {
+				t.Errorf("backing off: got %+v; want 1 active loop and 1 retry", s)
+			}
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// Loops that finish for other reasons.
+	n := 0
+	_ = New(ConstantBackoff(0), pr.Option()).Do(context.Background(), func() error {
+		if n++; n < 2 {
+			return errTest
+		}
+		return nil
+	})
+	_ = New(ConstantBackoff(0), pr.Option()).Do(context.Background(), func() error {
+		return NewPermanentError(errTest)
+	})
+	deadline, cancelDeadline := context.WithTimeout(context.Background(), time.Hour)
+	defer cancelDeadline()
+	_ = New(ConstantBackoff(2*time.Hour), pr.Option()).Do(deadline, func() error { return errTest })
+	cancel()
+	<-done
+
+	s := pr.snapshot(time.Now())
+	if s.Active != 0 || s.Sleeping != 0 {
+		t.Errorf("finished: got %d active and %d sleeping loops; want none", s.Active, s.Sleeping)
+	}
+	if s.Retries != 1 {
+		t.Errorf("retries: got %d; want 1", s.Retries)
+	}
+	want := map[Reason]int{ReasonPermanent: 1, ReasonDeadline: 1, ReasonContextDone: 1}
+	if s.GiveUps != 3 || len(s.Reasons) != len(want) {
+		t.Fatalf("give-ups: got %d %v; want 3 %v", s.GiveUps, s.Reasons, want)
+	}
+	for reason, n := range want {
+		if s.Reasons[reason] != n {
+			t.Errorf("give-ups for %v: got %d; want %d", reason, s.Reasons[reason], n)
+		}
+	}
+}
+
+func TestNewPressureReporterInterval(t *testing.T) {
+	pr := NewPressureReporter(0, func(PressureSnapshot) {})
+	pr.Stop()
+}
@@ -0,0 +1,51 @@
+// SPDX-License-Identifier: MIT
+//
+// Copyright 2022 Andrew Bursavich. All rights reserved.
+// Use of this source code is governed by The MIT License
+// which can be found in the LICENSE file.
+
+package retry
+
+import (
+	"strconv"
+	"time"
+)
+
+// ProgressKind is the kind of a Progress event.
+type ProgressKind int
+
+// Kinds of Progress events.
+const (
+	// AttemptStarted indicates that an attempt is starting.
+	AttemptStarted ProgressKind = iota
+	// AttemptFailed indicates that an attempt failed with an error.
+	AttemptFailed
+	// BackingOff indicates that the retry loop is waiting before the next attempt.
+	BackingOff
+)
+
+var progressKindNames = [...]string{
+	AttemptStarted: "attempt started",
+	AttemptFailed:  "attempt failed",
+	BackingOff:     "backing off",
+}
+
+func (k ProgressKind) String() string {
+	if k < 0 || int(k) >= len(progressKindNames) {
+		return "ProgressKind(" + strconv.Itoa(int(k)) + ")"
+	}
+	return progressKindNames[k]
+}
+
+// Progress is an event describing the progress of a retry loop.
+type Progress struct {
+	// Kind is the kind of the event.
+	Kind ProgressKind
+	// Attempt is the number of the attempt, starting at 1. If the kind is BackingOff,
+	// it's the number of the attempt that will be made after the backoff.
+	Attempt int
+	// Err is the error returned by the attempt if the kind is AttemptFailed.
+	Err error
+	// Backoff is the backoff duration if the kind is BackingOff.
+	Backoff time.Duration
+}
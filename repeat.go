@@ -0,0 +1,77 @@
+// SPDX-License-Identifier: MIT
+//
+// Copyright 2022 Andrew Bursavich. All rights reserved.
+// Use of this source code is governed by The MIT License
+// which can be found in the LICENSE file.
+
+package retry
+
+import "context"
+
+// Repeat invokes fn repeatedly, regardless of whether it succeeds, waiting for the
+// given policy's backoff between invocations, with the given Retrier options. The policy's
+// attempts and elapsed time are reset after each success, so its schedule applies to the
+// invocations since then. It covers token refreshers, cache refreshers, and heartbeats
+// with the same policy vocabulary as retry loops.
+//
+// For example, a heartbeat that's sent every 10s until 5 consecutive failures:
+//
+//	err := retry.Repeat(ctx, retry.WithMaxRetries(retry.ConstantBackoff(10*time.Second), 5), heartbeat)
+//
+// Repeat returns when fn returns a permanent error, when the policy stops, in which
+// case it returns the last error, which is nil after a success, or when ctx is done,
+// in which case it returns the context's error. A tightened context, as returned by
+// WithTightening, caps the invocations and backoffs since the last success.
+func Repeat(ctx context.Context, policy Policy, fn func() error, opts ...Option) error {
+	r := New(policy, opts...)
+	var t Timer
+	tight := tighteningFrom(ctx)
+	s := State{Start: r.clock.Now()}
+	for {
+		err := fn()
+		if err != nil && IsPermanentJoin(err, r.precedence, r.joinMode) {
+			return err
+		}
+		now := r.clock.Now()
+		if err == nil {
+			s = State{Start: now}
+		}
+		s.Err, s.Now = err, now
+		s.Attempt++
+		backoff, ok := next(r.policy, &s)
+		if !ok || !tight.allows(s.Attempt) {
+			return err
+		}
+		backoff = tight.cap(backoff)
+		if notBefore, ok := NotBefore(err); ok {
+			backoff = max(backoff, notBefore.Sub(r.clock.Now()))
+			if tight.cap(backoff) < backoff {
+				return err
+			}
+		}
+		if !r.wait(ctx, &t, backoff) {
+			return ctx.Err()
+		}
+	}
+}
+
+// Watch invokes fn repeatedly like Repeat, except that it calls alert when fn fails
+// threshold times in a row, while it continues to retry. It separates "keep trying forever"
+// from "tell a human after N failures". The alert isn't repeated until after fn succeeds
+// and then fails threshold times in a row again. A threshold less than 1 is treated as 1,
+// so that the first failure alerts.
+func Watch(ctx context.Context, policy Policy, threshold int, alert func(failures int, err error), fn func() error, opts ...Option) error {
+	threshold = max(threshold, 1)
+	failures := 0
+	return Repeat(ctx, policy, func() error {
+		err := fn()
+		if err == nil {
+			failures = 0
+			return nil
+		}
+		if failures++; failures == threshold {
+			alert(failures, err)
+		}
+		return err
+	}, opts...)
+}
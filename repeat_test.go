@@ -0,0 +1,64 @@
+// SPDX-License-Identifier: MIT
+//
+// Copyright 2022 Andrew Bursavich. All rights reserved.
+// Use of this source code is governed by The MIT License
+// which can be found in the LICENSE file.
+
+package retry_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"bursavich.dev/retry"
+	"bursavich.dev/retry/retrytest"
+)
+
+func TestWatch(t *testing.T) {
+	tests := []struct {
+		threshold int
+		alerts    []int
+	}{
+		{threshold: -1, alerts: []int{1, 1}},
+		{threshold: 0, alerts: []int{1, 1}},
+		{threshold: 1, alerts: []int{1, 1}},
+		{threshold: 3, alerts: []int{3}},
+		{threshold: 5},
+	}
+	for _, tt := range tests {
+		// Two failures and a success, which is the first attempt since the last success,
+		// and then three failures until the policy stops.
+		results := []error{errTest, errTest, nil, errTest, errTest, errTest}
+		var alerts []int
+		calls := 0
+		err := retry.Watch(
+			context.Background(),
+			retry.WithMaxRetries(retry.ConstantBackoff(time.Second), 3),
+			tt.threshold,
+			func(failures int, err error) {
+				if !errors.Is(err, errTest) {
+					t.Errorf("threshold %d: alert: got %v; want %v", tt.threshold, err, errTest)
+				}
+				alerts = append(alerts, failures)
+			},
+			func() error {
+				err := results[calls]
+				calls++
+				return err
+			},
+			retry.WithClock(retrytest.NewAutoClock(time.Now())),
+		)
+		if !errors.Is(err, errTest) {
+			t.Errorf("threshold %d: Watch: got %v; want %v", tt.threshold, err, errTest)
+		}
+		if calls != len(results) {
+			t.Errorf("threshold %d: calls: got %d; want %d", tt.threshold, calls, len(results))
+		}
+		if !slices.Equal(alerts, tt.alerts) {
+			t.Errorf("threshold %d: alerts: got %v; want %v", tt.threshold, alerts, tt.alerts)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+// SPDX-License-Identifier: MIT
+//
+// Copyright 2022 Andrew Bursavich. All rights reserved.
+// Use of this source code is governed by The MIT License
+// which can be found in the LICENSE file.
+
+package retry
+
+import (
+	"context"
+	"time"
+)
+
+// Report contains statistics of a retry loop, such as for emitting metrics
+// or audit logs of a single call.
+type Report struct {
+	// Attempts is the number of attempts made.
+	Attempts int
+	// Elapsed is the total duration of the retry loop.
+	Elapsed time.Duration
+	// Sleeping is the total duration spent waiting for backoffs.
+	Sleeping time.Duration
+	// Errors are the errors returned by each failed attempt, in order.
+	Errors []error
+	// Runtime contains the changes in runtime statistics across each attempt, in order,
+	// if the Retrier was configured with WithRuntimeStats.
+	Runtime []RuntimeStats
+}
+
+func (r *Report) add(err error) {
+	r.Attempts++
+	if err != nil {
+		r.Errors = append(r.Errors, err)
+	}
+}
+
+// DoReport executes the retriable function according to the given policy, like Do,
+// and returns a Report of the retry loop.
+func DoReport(ctx context.Context, policy Policy, fn func() error) (Report, error) {
+	return New(policy).DoReport(ctx, fn)
+}
@@ -0,0 +1,678 @@
+// SPDX-License-Identifier: MIT
+//
+// Copyright 2022 Andrew Bursavich. All rights reserved.
+// Use of this source code is governed by The MIT License
+// which can be found in the LICENSE file.
+
+package retry
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"sync/atomic"
+	"time"
+
+	"bursavich.dev/retry/internal/hooks"
+)
+
+// An Option configures a Retrier.
+type Option interface {
+	apply(*Retrier)
+}
+
+type optionFunc func(*Retrier)
+
+func (fn optionFunc) apply(r *Retrier) { fn(r) }
+
+// WithSpinThreshold returns an Option that waits for backoffs shorter than d
+// by yielding the processor in a loop instead of scheduling a timer.
+//
+// At very high retry rates with tiny backoffs, timer churn can dominate the cost
+// of retrying. Spinning trades CPU for latency: it wakes up more precisely and
+// avoids timers, but it keeps a goroutine runnable for the entire backoff.
+// It should only be used with thresholds on the order of a millisecond or less.
+//
+// Backoffs that aren't positive never schedule a timer, regardless of the threshold.
+// Retriers with a Clock other than the system clock never spin on positive backoffs.
+func WithSpinThreshold(d time.Duration) Option {
+	return optionFunc(func(r *Retrier) {
+		r.spinThreshold = d
+	})
+}
+
+// WithPolicyDeadline returns an Option that provides the context's deadline to the
+// policy as State.Deadline, so that it can be accounted for in decision making
+// instead of only cutting off retries that would be scheduled after it.
+//
+// For example, WithMaxElapsedDuration treats the deadline as a max elapsed limit
+// and a StatePolicy may choose a last small backoff that fits before the deadline.
+// It's implied if the policy includes WithDeadlineTruncation.
+func WithPolicyDeadline() Option {
+	return optionFunc(func(r *Retrier) {
+		r.policyDeadline = true
+	})
+}
+
+// WithTimeCompression returns an Option that compresses time by the given factor,
+// so that the full schedule of a production policy can be exercised quickly by
+// soak tests without changing the policy's definition.
+//
+// Backoffs are divided by the factor and the policy observes elapsed time multiplied
+// by it. For example, with a factor of 100, a 5m backoff waits for 3s and a policy
+// that limits the elapsed duration to 10m gives up after 6s. Context deadlines
+// aren't compressed. Non-positive factors are ignored.
+func WithTimeCompression(factor float64) Option {
+	return optionFunc(func(r *Retrier) {
+		if factor > 0 {
+			r.timeScale = factor
+		}
+	})
+}
+
+// WithDeadlineExtension returns an Option that calls extend when the policy allows a retry
+// but the context's deadline would be exceeded before the next attempt. If extend returns
+// true and a deadline that allows waiting for the backoff, the retry loop continues with a
+// context derived from ctx with the new deadline; otherwise it gives up. For example, an
+// interactive tool may ask the user whether to keep trying.
+//
+// The derived context is canceled when ctx is canceled, but not when its original deadline
+// is exceeded. It's canceled by the Retrier when the retry loop finishes.
+func WithDeadlineExtension(extend func(ctx context.Context, err error, backoff time.Duration) (deadline time.Time, ok bool)) Option {
+	return optionFunc(func(r *Retrier) {
+		r.extend = extend
+	})
+}
+
+// AttemptCost describes the cost of a single attempt.
+type AttemptCost struct {
+	// Attempt is the number of the attempt, starting at 1.
+	Attempt int
+	// Retry indicates if the attempt was a retry rather than the first attempt.
+	Retry bool
+	// Duration is the duration of the attempt.
+	Duration time.Duration
+	// Err is the error returned by the attempt.
+	Err error
+}
+
+// WithAttemptAccounting returns an Option that calls account after each attempt with its cost.
+// It allows platform teams to attribute the fraction of downstream cost and latency
+// that's caused by retries.
+func WithAttemptAccounting(account func(AttemptCost)) Option {
+	return optionFunc(func(r *Retrier) {
+		r.account = account
+	})
+}
+
+// WithAttemptTimeout returns an Option that limits the duration of each attempt made by DoCtx.
+// Each attempt's context has its own deadline d after the attempt starts, independent of the
+// overall deadline, so that a single hung attempt can't consume the whole retry budget.
+// Non-positive durations are ignored.
+func WithAttemptTimeout(d time.Duration) Option {
+	return optionFunc(func(r *Retrier) {
+		if d > 0 {
+			r.attemptTimeout = d
+		}
+	})
+}
+
+// WithBeforeAttempt returns an Option that calls before prior to each attempt with the
+// Attempt's statistics. It's advisory: it allows the application to reduce the work of
+// later attempts, such as by using a smaller batch size or a lower quality mode, so that
+// graceful degradation is coordinated with retrying.
+//
+// The context returned by before is passed to the function by DoCtx, so it may carry
+// the degraded configuration. Other functions ignore it.
+func WithBeforeAttempt(before func(ctx context.Context, a Attempt) context.Context) Option {
+	return optionFunc(func(r *Retrier) {
+		r.before = before
+	})
+}
+
+// WithErrorAggregation returns an Option that makes a retry loop that doesn't succeed
+// return the errors of all of its attempts joined by errors.Join, rather than only the
+// error of the last attempt. When attempts fail for different reasons, such as DNS on
+// the first attempt and 503 on the second, the last error alone hides critical debugging
+// information.
+func WithErrorAggregation() Option {
+	return optionFunc(func(r *Retrier) {
+		r.aggregateErrors = true
+	})
+}
+
+// WithContextErrors returns an Option that makes a retry loop that stops because of its
+// context return the last error joined with the context's error, so that callers can
+// distinguish giving up because of the context from the policy stopping with errors.Is.
+//
+// If the context is done while backing off, the last error is joined with context.Cause.
+// If the next attempt would exceed the context's deadline, it's joined with
+// context.DeadlineExceeded, even though the deadline hasn't been exceeded yet.
+func WithContextErrors() Option {
+	return optionFunc(func(r *Retrier) {
+		r.contextErrors = true
+	})
+}
+
+// WithLastChance returns an Option that makes one final attempt just before the context's
+// deadline, instead of giving up early, when the policy allows a retry but its backoff would
+// exceed the deadline. The final attempt is made the margin before the deadline, so that it
+// has time to finish. It materially improves success rates for callers with tight budgets.
+//
+// If the deadline is less than the margin away, or if an error signaling backpressure
+// doesn't allow the final attempt to be made by then, the retry loop gives up as usual.
+func WithLastChance(margin time.Duration) Option {
+	return optionFunc(func(r *Retrier) {
+		r.lastChance, r.lastChanceMargin = true, max(margin, 0)
+	})
+}
+
+// WithRemainingEstimate returns an Option that provides an estimate of the number of
+// attempts that remain after each attempt as Attempt.Remaining, given the policy and
+// the context's deadline. For example, a function may switch to a cheaper degraded
+// query on the last likely attempt.
+//
+// The estimate uses the policy's analytical bounds, if it's provided by this package,
+// or samples it, so custom policies shouldn't have side effects in Next.
+func WithRemainingEstimate() Option {
+	return optionFunc(func(r *Retrier) {
+		r.estimate = true
+	})
+}
+
+// WithInitialDelay returns an Option that delays the first attempt by d, such as for
+// startup loops that wait for a dependency to come up, where the first attempt is almost
+// guaranteed to fail if it's made immediately. The delay counts toward the elapsed time.
+// If ctx is done during the delay, the retry loop gives up without any attempts and
+// returns an *Error with ReasonContextDone that wraps the context's error.
+func WithInitialDelay(d time.Duration) Option {
+	return optionFunc(func(r *Retrier) {
+		r.initialDelay = d
+	})
+}
+
+// WithName returns an Option that names the Retrier, such as for identifying it in telemetry.
+func WithName(name string) Option {
+	return optionFunc(func(r *Retrier) {
+		r.name = name
+	})
+}
+
+// A Retrier executes retriable functions according to a Policy and a set of Options
+// that provide cross-cutting configuration. It's safe for concurrent use.
+type Retrier struct {
+	name             string
+	policy           Policy
+	spinThreshold    time.Duration
+	policyDeadline   bool
+	timeScale        float64
+	clock            Clock
+	explain          func(*Explanation)
+	extend           func(context.Context, error, time.Duration) (time.Time, bool)
+	account          func(AttemptCost)
+	attemptTimeout   time.Duration
+	coalescer        *Coalescer
+	coalesceKey      string
+	observers        []Observer
+	aggregateErrors  bool
+	before           func(context.Context, Attempt) context.Context
+	contextErrors    bool
+	lastChance       bool
+	lastChanceMargin time.Duration
+	gate             *Health
+	gateKey          string
+	estimate         bool
+	initialDelay     time.Duration
+	precedence       Precedence
+	joinMode         JoinMode
+	runtimeStats     bool
+}
+
+// New returns a new Retrier with the given policy and options.
+func New(policy Policy, opts ...Option) *Retrier {
+	r := &Retrier{policy: policy, clock: systemClock{}, policyDeadline: usesDeadline(policy)}
+	for _, o := range opts {
+		o.apply(r)
+	}
+	return r
+}
+
+// Name returns the name of the Retrier.
+func (r *Retrier) Name() string {
+	return r.name
+}
+
+// Policy returns the policy of the Retrier.
+func (r *Retrier) Policy() Policy {
+	return r.policy
+}
+
+// Do executes the retriable function according to the Retrier's policy.
+//
+// If fn returns a permanent error, it's returned without additional retry attempts.
+// Like any error that stops retrying, it's wrapped in an *Error, so it must be
+// inspected with errors.Is or errors.As rather than compared directly.
+//
+// If fn returns an error signaling backpressure, the next attempt won't be made before
+// the time it specifies.
+//
+// If ctx has a deadline before the next retry attempt would be scheduled it will return the
+// last error without waiting for the deadline.
+//
+// If retrying stops without a successful attempt, the last error is returned wrapped in an *Error.
+func (r *Retrier) Do(ctx context.Context, fn func() error) error {
+	return r.run(ctx, loop{fn: ignoreCtx(fn)})
+}
+
+// DoCtx executes the retriable function according to the Retrier's policy.
+// Each attempt receives its own context derived from ctx, which is canceled
+// when the attempt returns. The Attempt can be retrieved from the context
+// with AttemptFromContext.
+//
+// If fn returns a permanent error, it's returned without additional retry attempts.
+// Like any error that stops retrying, it's wrapped in an *Error, so it must be
+// inspected with errors.Is or errors.As rather than compared directly.
+//
+// If fn returns an error signaling backpressure, the next attempt won't be made before
+// the time it specifies.
+//
+// If ctx has a deadline before the next retry attempt would be scheduled it will return the
+// last error without waiting for the deadline.
+func (r *Retrier) DoCtx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return r.run(ctx, loop{fn: fn, attemptCtx: true})
+}
+
+// DoValueWith executes the retriable function according to the Retrier and returns the results.
+// It's the equivalent of a DoValue method, which can't be declared because methods can't have
+// type parameters.
+//
+// If fn returns a permanent error, it's returned without additional retry attempts.
+// Like any error that stops retrying, it's wrapped in an *Error, so it must be
+// inspected with errors.Is or errors.As rather than compared directly.
+//
+// If fn returns an error signaling backpressure, the next attempt won't be made before
+// the time it specifies.
+//
+// If ctx has a deadline before the next retry attempt would be scheduled it will return the
+// last error without waiting for the deadline.
+func DoValueWith[T any](ctx context.Context, r *Retrier, fn func() (T, error)) (T, error) {
+	return doValue(ctx, r, fn)
+}
+
+// A loop describes a single execution of the retry loop.
+type loop struct {
+	// fn is the retriable function.
+	fn func(context.Context) error
+	// attemptCtx indicates if fn uses its context, in which case
+	// a context is derived for each attempt.
+	attemptCtx bool
+	// progress, if non-nil, is called with the loop's progress.
+	progress func(Progress)
+	// interrupt, if non-nil, is called after each failed attempt that isn't permanent
+	// and stops the loop without giving up if it returns true.
+	interrupt func(ctx context.Context, err error) bool
+	// report, if non-nil, is filled with the loop's attempts, errors, and sleeping duration.
+	report *Report
+
+	// ex is the loop's explanation, which is set by run if the Retrier explains its decisions.
+	ex *Explanation
+	// limit is the Limit that stopped the policy, which is set by run before it stops.
+	limit Limit
+}
+
+// ignoreCtx adapts a retriable function that doesn't take a context.
+func ignoreCtx(fn func() error) func(context.Context) error {
+	return func(context.Context) error { return fn() }
+}
+
+// DoReport executes the retriable function according to the Retrier's policy, like Do,
+// and returns a Report of the retry loop.
+func (r *Retrier) DoReport(ctx context.Context, fn func() error) (Report, error) {
+	var rep Report
+	start := r.clock.Now()
+	err := r.run(ctx, loop{fn: ignoreCtx(fn), report: &rep})
+	rep.Elapsed = r.clock.Now().Sub(start)
+	return rep, err
+}
+
+// loopIDs is the source of Attempt.Loop.
+var loopIDs atomic.Uint64
+
+// run executes the retry loop. Changes to its transitions must be reflected by StateMachine.
+func (r *Retrier) run(ctx context.Context, l loop) error {
+	progress := l.progress
+	var t Timer
+	start := r.clock.Now()
+	deadline, hasDeadline := ctx.Deadline()
+	s := State{Start: start}
+	if r.policyDeadline && hasDeadline {
+		s.Deadline = r.virtual(start, deadline)
+	}
+	if r.explain != nil {
+		l.ex = &Explanation{}
+	}
+	if r.aggregateErrors && l.report == nil {
+		l.report = &Report{}
+	}
+	ex := l.ex
+	orig := ctx
+	var cancels []context.CancelFunc // of contexts with extended deadlines
+	defer func() {
+		for _, cancel := range cancels {
+			cancel()
+		}
+	}()
+	tight := tighteningFrom(ctx)
+	lastChance := false // whether the last-chance attempt was scheduled
+	a := Attempt{LoopStart: start, Loop: loopIDs.Add(1), Remaining: -1}
+	var slept time.Duration // total time spent waiting
+	if r.initialDelay > 0 {
+		if !r.wait(ctx, &t, r.initialDelay) {
+			return r.stop(ctx, &l, a, ctx.Err(), ReasonContextDone)
+		}
+		slept = r.clock.Now().Sub(start)
+		if l.report != nil {
+			l.report.Sleeping = slept
+		}
+	}
+	for n := 1; ; n++ {
+		if progress != nil {
+			progress(Progress{Kind: AttemptStarted, Attempt: n})
+		}
+		a.Number, a.Start = n, r.clock.Now()
+		a.End, a.BackoffStart = time.Time{}, time.Time{}
+		if r.estimate {
+			a.Remaining = r.estimateRemaining(a, slept, deadline, hasDeadline, tight)
+		}
+		for _, o := range r.observers {
+			o.AttemptStart(ctx, a)
+		}
+		var snap *runtimeSnapshot
+		if r.runtimeStats && l.report != nil {
+			snap = readRuntime()
+		}
+		err := r.attempt(ctx, a, l)
+		a.End = r.clock.Now()
+		if l.report != nil {
+			l.report.add(err)
+			if snap != nil {
+				l.report.Runtime = append(l.report.Runtime, snap.since(n))
+			}
+		}
+		for _, o := range r.observers {
+			o.AttemptEnd(ctx, a, err)
+		}
+		if err != nil && progress != nil {
+			progress(Progress{Kind: AttemptFailed, Attempt: n, Err: err})
+		}
+		if err == nil {
+			return r.stop(ctx, &l, a, err, ReasonNone)
+		}
+		if IsPermanentJoin(err, r.precedence, r.joinMode) {
+			// We don't return a permanentError's inner error because the permanentError
+			// may be in the middle of a chain of errors and we don't want to drop any
+			// errors that are wrapping it.
+			return r.stop(ctx, &l, a, err, ReasonPermanent)
+		}
+		if l.interrupt != nil && l.interrupt(ctx, err) {
+			return r.stop(ctx, &l, a, err, ReasonNone)
+		}
+
+		s.Err, s.Now = err, r.virtual(start, r.clock.Now())
+		s.Attempt++
+		attempt := s.Attempt
+		var steps []Step
+		if ex != nil {
+			s.steps = &steps
+		}
+		s.hit = LimitNone
+		backoff, ok := next(r.policy, &s)
+		if ex != nil {
+			ex.Decisions = append(ex.Decisions, Decision{
+				Attempt: attempt,
+				Err:     err,
+				Steps:   steps,
+				Backoff: backoff,
+				Retry:   ok,
+			})
+		}
+		if !ok || !tight.allows(n) {
+			if !ok {
+				l.limit = s.hit
+			}
+			return r.stop(ctx, &l, a, err, ReasonPolicy)
+		}
+		backoff = tight.cap(r.real(backoff))
+		notBefore, hasNotBefore := NotBefore(err)
+		if hasNotBefore {
+			backoff = max(backoff, notBefore.Sub(r.clock.Now()))
+			if tight.cap(backoff) < backoff {
+				return r.stop(ctx, &l, a, err, ReasonPolicy)
+			}
+		}
+		if hasDeadline && deadline.Before(r.clock.Now().Add(backoff)) {
+			switch {
+			case r.extendDeadline(orig, &ctx, &cancels, err, backoff):
+				deadline, hasDeadline = ctx.Deadline()
+				if r.policyDeadline {
+					s.Deadline = time.Time{}
+					if hasDeadline {
+						s.Deadline = r.virtual(start, deadline)
+					}
+				}
+			case r.lastChance && !lastChance && deadline.Sub(r.clock.Now()) >= r.lastChanceMargin &&
+				!(hasNotBefore && notBefore.After(deadline.Add(-r.lastChanceMargin))):
+				backoff = deadline.Sub(r.clock.Now()) - r.lastChanceMargin
+				if hasNotBefore {
+					backoff = max(backoff, notBefore.Sub(r.clock.Now()))
+				}
+				lastChance = true
+			default:
+				return r.stop(ctx, &l, a, err, ReasonDeadline)
+			}
+		}
+		if progress != nil {
+			progress(Progress{Kind: BackingOff, Attempt: n + 1, Backoff: backoff})
+		}
+		a.BackoffStart = r.clock.Now()
+		for _, o := range r.observers {
+			o.Backoff(ctx, a, err, backoff)
+		}
+		begin := r.clock.Now()
+		ok = r.wait(ctx, &t, backoff)
+		a.Woke = r.clock.Now()
+		d := a.Woke.Sub(begin)
+		slept += d
+		if l.report != nil {
+			l.report.Sleeping += d
+		}
+		if !ok {
+			return r.stop(ctx, &l, a, err, ReasonContextDone)
+		}
+		a.PrevErr, a.LastBackoff = err, backoff
+	}
+}
+
+// attempt calls the loop's function and accounts for its cost.
+func (r *Retrier) attempt(ctx context.Context, a Attempt, l loop) error {
+	if l.attemptCtx {
+		var cancel context.CancelFunc
+		if r.attemptTimeout > 0 {
+			ctx, cancel = context.WithTimeout(ctx, r.attemptTimeout)
+		} else {
+			ctx, cancel = context.WithCancel(ctx)
+		}
+		defer cancel()
+		ctx = context.WithValue(ctx, attemptKey{}, a)
+	}
+	if r.before != nil {
+		ctx = r.before(ctx, a)
+	}
+	if r.account == nil {
+		return l.fn(ctx)
+	}
+	err := l.fn(ctx)
+	r.account(AttemptCost{
+		Attempt:  a.Number,
+		Retry:    a.Number > 1,
+		Duration: r.clock.Now().Sub(a.Start),
+		Err:      err,
+	})
+	return err
+}
+
+// extendDeadline tries to replace ctx with one that's derived from the loop's original context
+// and whose deadline allows waiting for the backoff. If it does, the new context's cancel
+// function is appended to cancels.
+func (r *Retrier) extendDeadline(orig context.Context, ctx *context.Context, cancels *[]context.CancelFunc, err error, backoff time.Duration) bool {
+	if r.extend == nil {
+		return false
+	}
+	deadline, ok := r.extend(*ctx, err, backoff)
+	if !ok || deadline.Before(r.clock.Now().Add(backoff)) {
+		return false
+	}
+	next, cancel := withExtendedDeadline(orig, deadline)
+	*ctx = next
+	*cancels = append(*cancels, cancel)
+	return true
+}
+
+// withExtendedDeadline returns a context derived from parent with the given deadline,
+// which may be later than the parent's deadline. It's canceled when parent is canceled,
+// but not when parent's deadline is exceeded.
+func withExtendedDeadline(parent context.Context, deadline time.Time) (context.Context, context.CancelFunc) {
+	ctx, cancelCause := context.WithCancelCause(context.WithoutCancel(parent))
+	ctx, cancel := context.WithDeadline(ctx, deadline)
+	stop := context.AfterFunc(parent, func() {
+		if !errors.Is(parent.Err(), context.DeadlineExceeded) {
+			cancelCause(context.Cause(parent))
+		}
+	})
+	return ctx, func() {
+		stop()
+		cancel()
+		cancelCause(context.Canceled)
+	}
+}
+
+// stop finishes the retry loop and returns its error.
+func (r *Retrier) stop(ctx context.Context, l *loop, a Attempt, err error, reason Reason) error {
+	if r.aggregateErrors && err != nil && len(l.report.Errors) > 1 {
+		err = errors.Join(l.report.Errors...)
+	}
+	if r.contextErrors && a.Number > 0 { // Otherwise err is the context's error.
+		switch reason {
+		case ReasonContextDone:
+			err = errors.Join(err, context.Cause(ctx))
+		case ReasonDeadline:
+			err = errors.Join(err, context.DeadlineExceeded)
+		}
+	}
+	if ex := l.ex; ex != nil {
+		switch {
+		case reason != ReasonNone:
+			ex.Outcome = reason.String()
+		case err == nil:
+			ex.Outcome = "succeeded"
+		default:
+			ex.Outcome = "interrupted"
+		}
+		r.explain(ex)
+	}
+	for _, o := range r.observers {
+		if f, ok := o.(finisher); ok {
+			f.finish(ctx, a, reason, err)
+		}
+	}
+	if reason == ReasonNone {
+		return err
+	}
+	for _, o := range r.observers {
+		o.GiveUp(ctx, a, reason, err)
+	}
+	return &Error{
+		err:      err,
+		reason:   reason,
+		attempts: a.Number,
+		elapsed:  r.clock.Now().Sub(a.LoopStart),
+		limit:    l.limit,
+	}
+}
+
+// virtual returns the time as observed by the policy.
+func (r *Retrier) virtual(start, t time.Time) time.Time {
+	if r.timeScale == 0 {
+		return t
+	}
+	return start.Add(time.Duration(float64(t.Sub(start)) * r.timeScale))
+}
+
+// real returns the real duration of the backoff chosen by the policy.
+func (r *Retrier) real(d time.Duration) time.Duration {
+	if r.timeScale == 0 {
+		return d
+	}
+	return time.Duration(float64(d) / r.timeScale)
+}
+
+// wait waits for the backoff duration, and for the health gate if there is one,
+// and reports whether it elapsed before ctx was done.
+func (r *Retrier) wait(ctx context.Context, t *Timer, d time.Duration) bool {
+	if !r.backoff(ctx, t, d) {
+		return false
+	}
+	return r.gate == nil || r.gate.wait(ctx, r.gateKey, r.clock) == nil
+}
+
+// backoff waits for the backoff duration and reports whether it elapsed before ctx was done.
+// The timer is allocated on first use and reused by subsequent calls.
+func (r *Retrier) backoff(ctx context.Context, t *Timer, d time.Duration) bool {
+	_, system := r.clock.(systemClock)
+	if system && d > 0 {
+		hooks.Sleep(d)
+	}
+	// Other clocks may only advance when a timer is started, so they never spin.
+	if d <= 0 || (system && d < r.spinThreshold) {
+		return r.spin(ctx, d)
+	}
+	if r.coalescer != nil {
+		return r.coalescer.wait(ctx, r.clock, r.coalesceKey, d)
+	}
+	if *t == nil {
+		*t = r.clock.NewTimer(d)
+	} else {
+		resetTimer(*t, d)
+	}
+	select {
+	case <-ctx.Done():
+		(*t).Stop()
+		return false
+	case <-(*t).C():
+		return true
+	}
+}
+
+func (r *Retrier) spin(ctx context.Context, d time.Duration) bool {
+	deadline := r.clock.Now().Add(d)
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		default:
+		}
+		if !r.clock.Now().Before(deadline) {
+			return true
+		}
+		runtime.Gosched()
+	}
+}
+
+func resetTimer(t Timer, d time.Duration) {
+	t.Stop()
+	select {
+	case <-t.C():
+	default:
+	}
+	t.Reset(d)
+}
@@ -0,0 +1,48 @@
+// SPDX-License-Identifier: MIT
+//
+// Copyright 2022 Andrew Bursavich. All rights reserved.
+// Use of this source code is governed by The MIT License
+// which can be found in the LICENSE file.
+
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWithExtendedDeadline(t *testing.T) {
+	t.Run("parent deadline", func(t *testing.T) {
+		parent, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+		defer cancel()
+		ctx, cancelExt := withExtendedDeadline(parent, time.Now().Add(time.Hour))
+		defer cancelExt()
+
+		<-parent.Done()
+		time.Sleep(10 * time.Millisecond)
+		if err := ctx.Err(); err != nil {
+			t.Errorf("extended context: got error %v after the parent's deadline; want nil", err)
+		}
+	})
+	t.Run("parent canceled", func(t *testing.T) {
+		errCause := errors.New("cause")
+		parent, cancel := context.WithCancelCause(context.Background())
+		ctx, cancelExt := withExtendedDeadline(parent, time.Now().Add(time.Hour))
+		defer cancelExt()
+
+		cancel(errCause)
+		<-ctx.Done()
+		if got := context.Cause(ctx); got != errCause {
+			t.Errorf("extended context: got cause %v; want %v", got, errCause)
+		}
+	})
+	t.Run("canceled", func(t *testing.T) {
+		ctx, cancelExt := withExtendedDeadline(context.Background(), time.Now().Add(time.Hour))
+		cancelExt()
+		if err := ctx.Err(); err != context.Canceled {
+			t.Errorf("extended context: got error %v; want %v", err, context.Canceled)
+		}
+	})
+}
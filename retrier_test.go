@@ -0,0 +1,522 @@
+// SPDX-License-Identifier: MIT
+//
+// Copyright 2022 Andrew Bursavich. All rights reserved.
+// Use of this source code is governed by The MIT License
+// which can be found in the LICENSE file.
+
+package retry_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"bursavich.dev/retry"
+	"bursavich.dev/retry/retrytest"
+)
+
+func TestLastChanceBackpressure(t *testing.T) {
+	tests := []struct {
+		name      string
+		notBefore time.Duration
+		starts    []time.Duration
+		reason    retry.Reason
+	}{
+		{
+			name:      "before last chance",
+			notBefore: 5 * time.Second,
+			starts:    []time.Duration{0, 9 * time.Second},
+			reason:    retry.ReasonPolicy,
+		},
+		{
+			name:      "after last chance",
+			notBefore: 9500 * time.Millisecond,
+			starts:    []time.Duration{0},
+			reason:    retry.ReasonDeadline,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Now()
+			clock := retrytest.NewAutoClock(now)
+			ctx, cancel := context.WithDeadline(context.Background(), now.Add(10*time.Second))
+			defer cancel()
+
+			r := retry.New(
+				retry.WithMaxRetries(retry.ConstantBackoff(time.Hour), 1),
+				retry.WithClock(clock),
+				retry.WithLastChance(time.Second),
+			)
+			var starts []time.Duration
+			err := r.Do(ctx, func() error {
+				starts = append(starts, clock.Now().Sub(now))
+				return retry.Backpressure(errTest, now.Add(tt.notBefore))
+			})
+
+			if !slices.Equal(starts, tt.starts) {
+				t.Errorf("starts: got %v; want %v", starts, tt.starts)
+			}
+			checkReason(t, err, tt.reason)
+		})
+	}
+}
+
+func checkReason(t *testing.T, err error, want retry.Reason) {
+	t.Helper()
+	var rerr *retry.Error
+	if !errors.As(err, &rerr) {
+		t.Fatalf("error: got %v; want *retry.Error", err)
+	}
+	if got := rerr.Reason(); got != want {
+		t.Errorf("reason: got %v; want %v", got, want)
+	}
+}
+
+func TestReasonLabel(t *testing.T) {
+	tests := map[retry.Reason]string{
+		retry.ReasonNone:        "none",
+		retry.ReasonPermanent:   "permanent",
+		retry.ReasonPolicy:      "policy_exhausted",
+		retry.ReasonDeadline:    "deadline",
+		retry.ReasonContextDone: "context_done",
+		retry.Reason(100):       "reason_100",
+	}
+	for reason, want := range tests {
+		if got := reason.Label(); got != want {
+			t.Errorf("Reason(%d).Label(): got %q; want %q", int(reason), got, want)
+		}
+	}
+}
+
+func TestDeadlineExtension(t *testing.T) {
+	now := time.Now()
+	clock := retrytest.NewAutoClock(now)
+	ctx, cancel := context.WithDeadline(context.Background(), now.Add(10*time.Second))
+	defer cancel()
+
+	extensions := 0
+	r := retry.New(
+		retry.WithMaxRetries(retry.ConstantBackoff(time.Minute), 2),
+		retry.WithClock(clock),
+		retry.WithDeadlineExtension(func(ctx context.Context, err error, backoff time.Duration) (time.Time, bool) {
+			extensions++
+			return clock.Now().Add(backoff + time.Second), extensions == 1
+		}),
+	)
+	var starts []time.Duration
+	err := r.DoCtx(ctx, func(ctx context.Context) error {
+		starts = append(starts, clock.Now().Sub(now))
+		if _, ok := ctx.Deadline(); !ok {
+			t.Errorf("attempt %d: context has no deadline", len(starts))
+		}
+		return errTest
+	})
+
+	if want := []time.Duration{0, time.Minute}; !slices.Equal(starts, want) {
+		t.Errorf("starts: got %v; want %v", starts, want)
+	}
+	if extensions != 2 {
+		t.Errorf("extensions: got %d; want 2", extensions)
+	}
+	checkReason(t, err, retry.ReasonDeadline)
+}
+
+func TestErrorLimit(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy retry.Policy
+		want   retry.Limit
+	}{
+		{
+			name:   "max retries",
+			policy: retry.WithLimits(retry.ConstantBackoff(time.Second), 2, time.Hour),
+			want:   retry.LimitRetries,
+		},
+		{
+			name:   "max elapsed",
+			policy: retry.WithLimits(retry.ConstantBackoff(time.Minute), 100, 90*time.Second),
+			want:   retry.LimitElapsed,
+		},
+		{
+			name:   "parent",
+			policy: retry.WithLimits(retry.ScheduleBackoff([]time.Duration{time.Second}, retry.ExhaustStop), 100, time.Hour),
+			want:   retry.LimitParent,
+		},
+		{
+			name:   "nested",
+			policy: retry.WithLimits(retry.WithLimits(retry.ConstantBackoff(time.Second), 1, time.Hour), 100, time.Hour),
+			want:   retry.LimitRetries,
+		},
+		{
+			name:   "parent max retries",
+			policy: retry.WithLimits(retry.WithMaxRetries(retry.ConstantBackoff(time.Second), 1), 100, time.Hour),
+			want:   retry.LimitRetries,
+		},
+		{
+			name:   "WithMaxRetries",
+			policy: retry.WithMaxRetries(retry.ConstantBackoff(time.Second), 1),
+			want:   retry.LimitRetries,
+		},
+		{
+			name:   "WithMaxElapsedDuration",
+			policy: retry.WithMaxElapsedDuration(retry.ConstantBackoff(time.Minute), 90*time.Second),
+			want:   retry.LimitElapsed,
+		},
+		{
+			name:   "Fastest",
+			policy: retry.Fastest(retry.ConstantBackoff(time.Second), retry.WithMaxElapsedDuration(retry.ConstantBackoff(time.Minute), 90*time.Second)),
+			want:   retry.LimitElapsed,
+		},
+		{
+			name:   "no limits",
+			policy: retry.ScheduleBackoff([]time.Duration{time.Second}, retry.ExhaustStop),
+			want:   retry.LimitNone,
+		},
+		{
+			name:   "Never",
+			policy: retry.Never(),
+			want:   retry.LimitNone,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := retry.New(tt.policy, retry.WithClock(retrytest.NewAutoClock(time.Now())))
+			err := r.Do(context.Background(), func() error { return errTest })
+			checkReason(t, err, retry.ReasonPolicy)
+			var rerr *retry.Error
+			errors.As(err, &rerr)
+			if got := rerr.Limit(); got != tt.want {
+				t.Errorf("limit: got %v; want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInitialDelayContextDone(t *testing.T) {
+	now := time.Now()
+	clock := retrytest.NewClock(now)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	r := retry.New(
+		retry.ConstantBackoff(time.Second),
+		retry.WithClock(clock),
+		retry.WithInitialDelay(time.Minute),
+		retry.WithContextErrors(),
+	)
+	attempts := 0
+	err := r.Do(ctx, func() error {
+		attempts++
+		return nil
+	})
+
+	if attempts != 0 {
+		t.Errorf("attempts: got %d; want 0", attempts)
+	}
+	checkReason(t, err, retry.ReasonContextDone)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("error: got %v; want %v", err, context.Canceled)
+	}
+	if got := err.Error(); got != context.Canceled.Error() {
+		t.Errorf("message: got %q; want %q", got, context.Canceled.Error())
+	}
+}
+
+func TestPermanentErrorWrapped(t *testing.T) {
+	perm := retry.NewPermanentError(errTest)
+	err := retry.Do(context.Background(), retry.ConstantBackoff(time.Second), func() error { return perm })
+
+	if err == perm {
+		t.Error("error: got the permanent error; want it wrapped in *retry.Error")
+	}
+	if !errors.Is(err, perm) || !errors.Is(err, errTest) {
+		t.Errorf("error: got %v; want it to wrap %v", err, perm)
+	}
+	checkReason(t, err, retry.ReasonPermanent)
+}
+
+func TestRetrierLoop(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   retry.Policy
+		opts     []retry.Option
+		manual   bool          // whether the clock only advances when it's told to
+		deadline time.Duration // of the context, if positive
+		attempt  func(n int, start time.Time, cancel func()) error
+		starts   []time.Duration
+		reason   retry.Reason // ReasonNone if the loop succeeds
+	}{
+		{
+			name:    "success",
+			policy:  retry.ConstantBackoff(time.Second),
+			attempt: func(int, time.Time, func()) error { return nil },
+			starts:  []time.Duration{0},
+		},
+		{
+			name:   "success after retries",
+			policy: retry.ConstantBackoff(time.Second),
+			attempt: func(n int, _ time.Time, _ func()) error {
+				if n < 3 {
+					return errTest
+				}
+				return nil
+			},
+			starts: []time.Duration{0, time.Second, 2 * time.Second},
+		},
+		{
+			name:   "permanent",
+			policy: retry.ConstantBackoff(time.Second),
+			attempt: func(n int, _ time.Time, _ func()) error {
+				if n < 2 {
+					return errTest
+				}
+				return retry.NewPermanentError(errTest)
+			},
+			starts: []time.Duration{0, time.Second},
+			reason: retry.ReasonPermanent,
+		},
+		{
+			name:    "policy",
+			policy:  retry.WithMaxRetries(retry.ConstantBackoff(time.Second), 2),
+			attempt: func(int, time.Time, func()) error { return errTest },
+			starts:  []time.Duration{0, time.Second, 2 * time.Second},
+			reason:  retry.ReasonPolicy,
+		},
+		{
+			name:     "deadline",
+			policy:   retry.ConstantBackoff(time.Minute),
+			deadline: 90 * time.Second,
+			attempt:  func(int, time.Time, func()) error { return errTest },
+			starts:   []time.Duration{0, time.Minute},
+			reason:   retry.ReasonDeadline,
+		},
+		{
+			name:   "backpressure",
+			policy: retry.WithMaxRetries(retry.ConstantBackoff(time.Second), 2),
+			attempt: func(n int, start time.Time, _ func()) error {
+				if n == 1 {
+					return retry.Backpressure(errTest, start.Add(5*time.Second))
+				}
+				return errTest
+			},
+			starts: []time.Duration{0, 5 * time.Second, 6 * time.Second},
+			reason: retry.ReasonPolicy,
+		},
+		{
+			name:   "initial delay",
+			policy: retry.ConstantBackoff(time.Second),
+			opts:   []retry.Option{retry.WithInitialDelay(10 * time.Second)},
+			attempt: func(n int, _ time.Time, _ func()) error {
+				if n < 2 {
+					return errTest
+				}
+				return nil
+			},
+			starts: []time.Duration{10 * time.Second, 11 * time.Second},
+		},
+		{
+			name:   "spin threshold",
+			policy: retry.ConstantBackoff(time.Second),
+			opts:   []retry.Option{retry.WithSpinThreshold(time.Hour)},
+			attempt: func(n int, _ time.Time, _ func()) error {
+				if n < 2 {
+					return errTest
+				}
+				return nil
+			},
+			starts: []time.Duration{0, time.Second},
+		},
+		{
+			name:   "canceled while backing off",
+			policy: retry.ConstantBackoff(time.Second),
+			manual: true,
+			attempt: func(_ int, _ time.Time, cancel func()) error {
+				cancel()
+				return errTest
+			},
+			starts: []time.Duration{0},
+			reason: retry.ReasonContextDone,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := time.Now()
+			clock := retrytest.NewAutoClock(start)
+			if tt.manual {
+				clock = retrytest.NewClock(start)
+			}
+			ctx, cancel := context.WithCancel(context.Background())
+			if tt.deadline > 0 {
+				ctx, cancel = context.WithDeadline(context.Background(), start.Add(tt.deadline))
+			}
+			defer cancel()
+
+			opts := append([]retry.Option{retry.WithClock(clock)}, tt.opts...)
+			r := retry.New(tt.policy, opts...)
+			var starts []time.Duration
+			err := r.Do(ctx, func() error {
+				starts = append(starts, clock.Now().Sub(start))
+				return tt.attempt(len(starts), start, cancel)
+			})
+
+			if !slices.Equal(starts, tt.starts) {
+				t.Errorf("starts: got %v; want %v", starts, tt.starts)
+			}
+			if tt.reason == retry.ReasonNone {
+				if err != nil {
+					t.Errorf("error: got %v; want nil", err)
+				}
+				return
+			}
+			checkReason(t, err, tt.reason)
+			if !errors.Is(err, errTest) {
+				t.Errorf("error: got %v; want %v", err, errTest)
+			}
+		})
+	}
+}
+
+func TestHelperOptions(t *testing.T) {
+	policy := retry.ConstantBackoff(time.Hour)
+	tests := []struct {
+		name string
+		run  func(ctx context.Context, fn func() error, opts ...retry.Option) error
+	}{
+		{
+			name: "Go",
+			run: func(ctx context.Context, fn func() error, opts ...retry.Option) error {
+				return retry.Go(ctx, policy, fn, opts...).Wait()
+			},
+		},
+		{
+			name: "DoThenBackground",
+			run: func(ctx context.Context, fn func() error, opts ...retry.Option) error {
+				h, err := retry.DoThenBackground(ctx, policy, 2, fn, opts...)
+				if h != nil {
+					return h.Wait()
+				}
+				return err
+			},
+		},
+		{
+			name: "DoValueCached",
+			run: func(ctx context.Context, fn func() error, opts ...retry.Option) error {
+				lookup := func(context.Context) (int, bool) { return 0, false }
+				_, err := retry.DoValueCached(ctx, policy, func() (int, error) { return 0, fn() }, lookup, opts...)
+				return err
+			},
+		},
+		{
+			name: "DoValueCachedRefresh",
+			run: func(ctx context.Context, fn func() error, opts ...retry.Option) error {
+				lookup := func(context.Context) (int, bool) { return 0, false }
+				_, _, err := retry.DoValueCachedRefresh(ctx, policy, func() (int, error) { return 0, fn() }, lookup, opts...)
+				return err
+			},
+		},
+		{
+			name: "Repeat",
+			run: func(ctx context.Context, fn func() error, opts ...retry.Option) error {
+				return repeatUntilSuccess(fn, func(fn func() error) error {
+					return retry.Repeat(ctx, policy, fn, opts...)
+				})
+			},
+		},
+		{
+			name: "Watch",
+			run: func(ctx context.Context, fn func() error, opts ...retry.Option) error {
+				return repeatUntilSuccess(fn, func(fn func() error) error {
+					return retry.Watch(ctx, policy, 1, func(int, error) {}, fn, opts...)
+				})
+			},
+		},
+		{
+			name: "Stream",
+			run: func(ctx context.Context, fn func() error, opts ...retry.Option) error {
+				produce := func(ctx context.Context, token string, emit func(int) error) (string, error) {
+					return "", fn()
+				}
+				return retry.Stream(ctx, policy, produce, func(int) error { return nil }, opts...)
+			},
+		},
+		{
+			name: "Wrap",
+			run: func(ctx context.Context, fn func() error, opts ...retry.Option) error {
+				return retry.Wrap(policy, func(context.Context) error { return fn() }, opts...)(ctx)
+			},
+		},
+		{
+			name: "WrapValue",
+			run: func(ctx context.Context, fn func() error, opts ...retry.Option) error {
+				wrapped := retry.WrapValue(policy, func(context.Context) (int, error) { return 0, fn() }, opts...)
+				_, err := wrapped(ctx)
+				return err
+			},
+		},
+		{
+			name: "Attempts",
+			run: func(ctx context.Context, fn func() error, opts ...retry.Option) error {
+				for _, err := range retry.Attempts(ctx, policy, opts...) {
+					if err != nil {
+						return err
+					}
+					if err := fn(); err == nil {
+						return nil
+					}
+				}
+				return nil
+			},
+		},
+		{
+			name: "BatchFlusher",
+			run: func(ctx context.Context, fn func() error, opts ...retry.Option) error {
+				b := retry.NewBatchFlusher(
+					policy,
+					func(context.Context, []int) error { return fn() },
+					retry.WithBatchRetrierOptions[int](opts...),
+				)
+				_, err := b.Flush(ctx, []int{1})
+				return err
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Now()
+			clock := retrytest.NewAutoClock(now)
+			fails := 1
+			err := tt.run(context.Background(), func() error {
+				if fails > 0 {
+					fails--
+					return errTest
+				}
+				return nil
+			}, retry.WithClock(clock))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			// The fake clock was advanced by the backoff, so no real time was spent waiting.
+			if got, want := clock.Now().Sub(now), time.Hour; got != want {
+				t.Errorf("elapsed: got %v; want %v", got, want)
+			}
+		})
+	}
+}
+
+// repeatUntilSuccess calls repeat with a function that calls fn and stops
+// repeating after fn succeeds.
+func repeatUntilSuccess(fn func() error, repeat func(func() error) error) error {
+	errDone := errors.New("done")
+	err := repeat(func() error {
+		if err := fn(); err != nil {
+			return err
+		}
+		return retry.NewPermanentError(errDone)
+	})
+	if errors.Is(err, errDone) {
+		return nil
+	}
+	return err
+}
@@ -44,95 +44,101 @@ func (e *permanentError) Unwrap() error { return e.err }
 
 func (e *permanentError) Is(err error) bool { return err == e || err == permErr }
 
+// Backpressure returns a new error that wraps err and signals that the next attempt
+// must not be made before notBefore, such as when an API responds with an absolute
+// rate limit reset time. If err is nil, it returns nil.
+//
+// The retry loop waits until at least notBefore if its policy allows a retry.
+func Backpressure(err error, notBefore time.Time) error {
+	if err == nil {
+		return nil
+	}
+	return &backpressureError{err: err, notBefore: notBefore}
+}
+
+// NotBefore returns the time before which the next attempt must not be made
+// if err signals backpressure.
+func NotBefore(err error) (time.Time, bool) {
+	var e *backpressureError
+	if !errors.As(err, &e) {
+		return time.Time{}, false
+	}
+	return e.notBefore, true
+}
+
+type backpressureError struct {
+	err       error
+	notBefore time.Time
+}
+
+func (e *backpressureError) Error() string { return e.err.Error() }
+
+func (e *backpressureError) Unwrap() error { return e.err }
+
 // Do executes the retriable function according to the given policy.
 //
-// If fn returns a permanent error, the error will be returned without additional retry attempts.
+// If fn returns a permanent error, it's returned without additional retry attempts.
+// Like any error that stops retrying, it's wrapped in an *Error, so it must be
+// inspected with errors.Is or errors.As rather than compared directly.
+//
+// If fn returns an error signaling backpressure, the next attempt won't be made before
+// the time it specifies.
 //
 // If ctx has a deadline before the next retry attempt would be scheduled it will return the
 // last error without waiting for the deadline.
+//
+// If retrying stops without a successful attempt, the last error is returned wrapped in an *Error.
 func Do(ctx context.Context, policy Policy, fn func() error) error {
-	var t *time.Timer
-	start := time.Now()
-	deadline, hasDeadline := ctx.Deadline()
-	for retry := 1; ; retry++ {
-		err := fn()
-		if err == nil || isPermErr(err) {
-			// We don't return a permanentError's inner error because the permanentError
-			// may be in the middle of a chain of errors and we don't want to drop any
-			// errors that are wrapping it.
-			return err
-		}
-
-		now := time.Now()
-		next, ok := policy.Next(err, start, now, retry)
-		if !ok {
-			return err
-		}
-		if hasDeadline && deadline.Before(time.Now().Add(next)) {
-			return err
-		}
-
-		if t == nil {
-			t = time.NewTimer(next)
-		} else {
-			resetTimer(t, next)
-		}
-		select {
-		case <-ctx.Done():
-			t.Stop()
-			return err
-		case <-t.C:
-		}
-	}
+	return New(policy).Do(ctx, fn)
 }
 
 // DoValue executes the retriable function according to the given policy and returns the results.
 //
-// If fn returns a permanent error, the error will be returned without additional retry attempts.
+// If fn returns a permanent error, it's returned without additional retry attempts.
+// Like any error that stops retrying, it's wrapped in an *Error, so it must be
+// inspected with errors.Is or errors.As rather than compared directly.
 //
 // If ctx has a deadline before the next retry attempt would be scheduled it will return the
 // last error without waiting for the deadline.
 func DoValue[T any](ctx context.Context, policy Policy, fn func() (T, error)) (T, error) {
-	var t *time.Timer
-	start := time.Now()
-	deadline, hasDeadline := ctx.Deadline()
-	for retry := 1; ; retry++ {
-		v, err := fn()
-		if err == nil || isPermErr(err) {
-			// We don't return a permanentError's inner error because the permanentError
-			// may be in the middle of a chain of errors and we don't want to drop any
-			// errors that are wrapping it.
-			return v, err
-		}
-
-		now := time.Now()
-		next, ok := policy.Next(err, start, now, retry)
-		if !ok {
-			return v, err
-		}
-		if hasDeadline && deadline.Before(time.Now().Add(next)) {
-			return v, err
-		}
-
-		if t == nil {
-			t = time.NewTimer(next)
-		} else {
-			resetTimer(t, next)
-		}
-		select {
-		case <-ctx.Done():
-			t.Stop()
-			return v, err
-		case <-t.C:
-		}
-	}
+	return doValue(ctx, New(policy), fn)
 }
 
-func resetTimer(t *time.Timer, d time.Duration) {
-	t.Stop()
-	select {
-	case <-t.C:
-	default:
-	}
-	t.Reset(d)
+// DoCtx executes the retriable function according to the given policy.
+// Each attempt receives its own context derived from ctx, which is canceled
+// when the attempt returns. The Attempt can be retrieved from the context
+// with AttemptFromContext.
+//
+// If fn returns a permanent error, it's returned without additional retry attempts.
+// Like any error that stops retrying, it's wrapped in an *Error, so it must be
+// inspected with errors.Is or errors.As rather than compared directly.
+//
+// If fn returns an error signaling backpressure, the next attempt won't be made before
+// the time it specifies.
+//
+// If ctx has a deadline before the next retry attempt would be scheduled it will return the
+// last error without waiting for the deadline.
+func DoCtx(ctx context.Context, policy Policy, fn func(ctx context.Context) error) error {
+	return New(policy).DoCtx(ctx, fn)
+}
+
+// DoFor executes the retriable function according to the given policy for up to the total duration.
+// It derives a context with the total timeout from parent and limits the policy's elapsed duration
+// to the same total, so that the two limits can't be set to conflicting values.
+//
+// The derived context is canceled when DoFor returns, so fn shouldn't retain it.
+func DoFor(parent context.Context, total time.Duration, policy Policy, fn func(ctx context.Context) error) error {
+	ctx, cancel := context.WithTimeout(parent, total)
+	defer cancel()
+	return Do(ctx, WithMaxElapsedDuration(policy, total), func() error { return fn(ctx) })
+}
+
+func doValue[T any](ctx context.Context, r *Retrier, fn func() (T, error)) (T, error) {
+	var v T
+	err := r.run(ctx, loop{fn: func(context.Context) error {
+		var err error
+		v, err = fn()
+		return err
+	}})
+	return v, err
 }
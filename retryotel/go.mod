@@ -0,0 +1,10 @@
+module bursavich.dev/retry/retryotel
+
+go 1.23
+
+require (
+	bursavich.dev/retry v0.1.0
+	go.opentelemetry.io/otel v1.31.0
+	go.opentelemetry.io/otel/metric v1.31.0
+	go.opentelemetry.io/otel/trace v1.31.0
+)
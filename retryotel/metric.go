@@ -0,0 +1,89 @@
+// SPDX-License-Identifier: MIT
+//
+// Copyright 2022 Andrew Bursavich. All rights reserved.
+// Use of this source code is governed by The MIT License
+// which can be found in the LICENSE file.
+
+package retryotel
+
+import (
+	"context"
+	"time"
+
+	"bursavich.dev/retry"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+const meterName = "bursavich.dev/retry/retryotel"
+
+// NameKey is the attribute key of the name of the retry loops recorded by Metrics.
+const NameKey = attribute.Key("retry.name")
+
+// Metrics contains the metric instruments of retry loops.
+type Metrics struct {
+	attempts metric.Int64Counter
+	giveUps  metric.Int64Counter
+	backoffs metric.Float64Histogram
+}
+
+// NewMetrics returns new Metrics with instruments created by a Meter from mp.
+func NewMetrics(mp metric.MeterProvider) (*Metrics, error) {
+	meter := mp.Meter(meterName)
+	attempts, err := meter.Int64Counter("retry.attempts",
+		metric.WithDescription("Number of attempts made by retry loops, including first attempts."),
+		metric.WithUnit("{attempt}"),
+	)
+	if err != nil {
+		return nil, err
+	}
+	giveUps, err := meter.Int64Counter("retry.give_ups",
+		metric.WithDescription("Number of retry loops that gave up without a successful attempt."),
+		metric.WithUnit("{loop}"),
+	)
+	if err != nil {
+		return nil, err
+	}
+	backoffs, err := meter.Float64Histogram("retry.backoff",
+		metric.WithDescription("Backoff durations waited by retry loops."),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &Metrics{
+		attempts: attempts,
+		giveUps:  giveUps,
+		backoffs: backoffs,
+	}, nil
+}
+
+// Observer returns a retry.Observer that records the metrics of retry loops
+// with the given name, such as the name of the Retrier.
+func (m *Metrics) Observer(name string) retry.Observer {
+	return &metricsObserver{
+		m:    m,
+		name: NameKey.String(name),
+		opts: metric.WithAttributeSet(attribute.NewSet(NameKey.String(name))),
+	}
+}
+
+type metricsObserver struct {
+	m    *Metrics
+	name attribute.KeyValue
+	opts metric.MeasurementOption
+}
+
+func (o *metricsObserver) AttemptStart(ctx context.Context, a retry.Attempt) {
+	o.m.attempts.Add(ctx, 1, o.opts)
+}
+
+func (o *metricsObserver) AttemptEnd(ctx context.Context, a retry.Attempt, err error) {}
+
+func (o *metricsObserver) Backoff(ctx context.Context, a retry.Attempt, err error, d time.Duration) {
+	o.m.backoffs.Record(ctx, d.Seconds(), o.opts)
+}
+
+func (o *metricsObserver) GiveUp(ctx context.Context, a retry.Attempt, reason retry.Reason, err error) {
+	o.m.giveUps.Add(ctx, 1, metric.WithAttributes(o.name, ReasonKey.String(reason.Label())))
+}
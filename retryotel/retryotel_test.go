@@ -0,0 +1,126 @@
+// SPDX-License-Identifier: MIT
+//
+// Copyright 2022 Andrew Bursavich. All rights reserved.
+// Use of this source code is governed by The MIT License
+// which can be found in the LICENSE file.
+
+package retryotel_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"bursavich.dev/retry"
+	"bursavich.dev/retry/retryotel"
+	"bursavich.dev/retry/retrytest"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	metricnoop "go.opentelemetry.io/otel/metric/noop"
+	"go.opentelemetry.io/otel/trace"
+	tracenoop "go.opentelemetry.io/otel/trace/noop"
+)
+
+var errTest = errors.New("test error")
+
+func TestObserver(t *testing.T) {
+	span := &recordingSpan{}
+	ctx := trace.ContextWithSpan(context.Background(), span)
+	r := retry.New(
+		retry.WithMaxRetries(retry.ConstantBackoff(time.Second), 1),
+		retry.WithObserver(retryotel.Observer{}),
+		retry.WithClock(retrytest.NewAutoClock(time.Now())),
+	)
+	_ = r.Do(ctx, func() error { return errTest })
+
+	want := []string{
+		"error: retry.attempt=1",
+		"retry.backoff: retry.attempt=1 retry.backoff_seconds=1",
+		"error: retry.attempt=2",
+		"retry.give_up: retry.attempt=2 retry.reason=policy_exhausted",
+	}
+	if !slices.Equal(span.events, want) {
+		t.Errorf("events:\ngot:\n%s\nwant:\n%s", strings.Join(span.events, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	var adds []string
+	m, err := retryotel.NewMetrics(&recordingMeterProvider{adds: &adds})
+	if err != nil {
+		t.Fatalf("NewMetrics: %v", err)
+	}
+	r := retry.New(
+		retry.ConstantBackoff(time.Second),
+		retry.WithObserver(m.Observer("backend")),
+		retry.WithClock(retrytest.NewAutoClock(time.Now())),
+	)
+	_ = r.Do(context.Background(), func() error { return retry.NewPermanentError(errTest) })
+
+	want := []string{
+		"retry.attempts: retry.name=backend",
+		"retry.give_ups: retry.name=backend retry.reason=permanent",
+	}
+	if !slices.Equal(adds, want) {
+		t.Errorf("adds:\ngot:\n%s\nwant:\n%s", strings.Join(adds, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+// recordingSpan is a recording trace.Span that records its events.
+type recordingSpan struct {
+	tracenoop.Span
+	events []string
+}
+
+func (s *recordingSpan) IsRecording() bool { return true }
+
+func (s *recordingSpan) AddEvent(name string, opts ...trace.EventOption) {
+	cfg := trace.NewEventConfig(opts...)
+	s.events = append(s.events, format(name, cfg.Attributes()))
+}
+
+func (s *recordingSpan) RecordError(err error, opts ...trace.EventOption) {
+	s.AddEvent("error", opts...)
+}
+
+// recordingMeterProvider is a metric.MeterProvider whose Int64Counters record their additions.
+type recordingMeterProvider struct {
+	metricnoop.MeterProvider
+	adds *[]string
+}
+
+func (p *recordingMeterProvider) Meter(string, ...metric.MeterOption) metric.Meter {
+	return &recordingMeter{adds: p.adds}
+}
+
+type recordingMeter struct {
+	metricnoop.Meter
+	adds *[]string
+}
+
+func (m *recordingMeter) Int64Counter(name string, _ ...metric.Int64CounterOption) (metric.Int64Counter, error) {
+	return &recordingCounter{name: name, adds: m.adds}, nil
+}
+
+type recordingCounter struct {
+	metricnoop.Int64Counter
+	name string
+	adds *[]string
+}
+
+func (c *recordingCounter) Add(ctx context.Context, incr int64, opts ...metric.AddOption) {
+	set := metric.NewAddConfig(opts).Attributes()
+	*c.adds = append(*c.adds, format(c.name, set.ToSlice()))
+}
+
+// format returns the name followed by the attributes in order.
+func format(name string, attrs []attribute.KeyValue) string {
+	parts := []string{name + ":"}
+	for _, kv := range attrs {
+		parts = append(parts, string(kv.Key)+"="+kv.Value.Emit())
+	}
+	return strings.Join(parts, " ")
+}
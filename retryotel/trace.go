@@ -0,0 +1,72 @@
+// SPDX-License-Identifier: MIT
+//
+// Copyright 2022 Andrew Bursavich. All rights reserved.
+// Use of this source code is governed by The MIT License
+// which can be found in the LICENSE file.
+
+// Package retryotel provides OpenTelemetry instrumentation for retry loops.
+//
+// It's a separate module, so that the retry module doesn't depend on OpenTelemetry.
+package retryotel
+
+import (
+	"context"
+	"time"
+
+	"bursavich.dev/retry"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Attribute keys recorded by the Observer. The value of ReasonKey is
+// the retry.Reason's Label, such as "policy_exhausted".
+const (
+	AttemptKey = attribute.Key("retry.attempt")
+	BackoffKey = attribute.Key("retry.backoff_seconds")
+	ReasonKey  = attribute.Key("retry.reason")
+)
+
+// Observer is a retry.Observer that records the lifecycle of retry loops
+// as events of the span in the loop's context. Retries that would otherwise
+// be invisible in traces can be seen when diagnosing tail latency.
+//
+// Failed attempts are recorded as error events with the attempt number.
+// Failed attempts and backoffs are recorded at the times given by the Attempt.
+// Backoffs and give-ups are recorded as "retry.backoff" and "retry.give_up" events.
+type Observer struct{}
+
+// AttemptStart implements retry.Observer.
+func (Observer) AttemptStart(ctx context.Context, a retry.Attempt) {}
+
+// AttemptEnd implements retry.Observer.
+func (Observer) AttemptEnd(ctx context.Context, a retry.Attempt, err error) {
+	span := trace.SpanFromContext(ctx)
+	if err == nil || !span.IsRecording() {
+		return
+	}
+	span.RecordError(err, trace.WithTimestamp(a.End), trace.WithAttributes(AttemptKey.Int(a.Number)))
+}
+
+// Backoff implements retry.Observer.
+func (Observer) Backoff(ctx context.Context, a retry.Attempt, err error, d time.Duration) {
+	span := trace.SpanFromContext(ctx)
+	if !span.IsRecording() {
+		return
+	}
+	span.AddEvent("retry.backoff", trace.WithTimestamp(a.BackoffStart), trace.WithAttributes(
+		AttemptKey.Int(a.Number),
+		BackoffKey.Float64(d.Seconds()),
+	))
+}
+
+// GiveUp implements retry.Observer.
+func (Observer) GiveUp(ctx context.Context, a retry.Attempt, reason retry.Reason, err error) {
+	span := trace.SpanFromContext(ctx)
+	if !span.IsRecording() {
+		return
+	}
+	span.AddEvent("retry.give_up", trace.WithAttributes(
+		AttemptKey.Int(a.Number),
+		ReasonKey.String(reason.Label()),
+	))
+}
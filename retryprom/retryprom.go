@@ -0,0 +1,174 @@
+// SPDX-License-Identifier: MIT
+//
+// Copyright 2022 Andrew Bursavich. All rights reserved.
+// Use of this source code is governed by The MIT License
+// which can be found in the LICENSE file.
+
+// Package retryprom provides Prometheus metrics for retry loops.
+//
+// It's a separate module, so that the retry module doesn't depend on Prometheus.
+package retryprom
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"bursavich.dev/retry"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// A Collector is a prometheus.Collector of the metrics of retry loops,
+// labeled by the name of the Retrier. Give-ups are also labeled by the
+// retry.Reason's Label, such as "policy_exhausted".
+type Collector struct {
+	attempts          *prometheus.CounterVec
+	successes         *prometheus.CounterVec
+	giveUps           *prometheus.CounterVec
+	backoffs          *prometheus.HistogramVec
+	attemptsToSuccess *prometheus.HistogramVec
+	policyParams      *prometheus.GaugeVec
+}
+
+// NewCollector returns a new Collector.
+func NewCollector() *Collector {
+	return &Collector{
+		attempts: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "retry_attempts_total",
+				Help: "Total number of attempts made by retry loops, including first attempts.",
+			},
+			[]string{"retrier"},
+		),
+		successes: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "retry_successes_total",
+				Help: "Total number of retry loops that finished with a successful attempt.",
+			},
+			[]string{"retrier"},
+		),
+		giveUps: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "retry_give_ups_total",
+				Help: "Total number of retry loops that gave up without a successful attempt.",
+			},
+			[]string{"retrier", "reason"},
+		),
+		backoffs: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "retry_backoff_seconds",
+				Help:    "Backoff durations waited by retry loops.",
+				Buckets: prometheus.ExponentialBuckets(0.01, 2, 14),
+			},
+			[]string{"retrier"},
+		),
+		attemptsToSuccess: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "retry_attempts_to_success",
+				Help:    "Number of attempts made by retry loops that finished with a successful attempt.",
+				Buckets: []float64{1, 2, 3, 4, 5, 7, 10, 15, 20, 30, 50},
+			},
+			[]string{"retrier"},
+		),
+		policyParams: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "retry_policy_param",
+				Help: "Static configuration of retry policies, by layer of the policy spec. Durations are in seconds.",
+			},
+			[]string{"retrier", "layer", "type", "param"},
+		),
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	c.attempts.Describe(ch)
+	c.successes.Describe(ch)
+	c.giveUps.Describe(ch)
+	c.backoffs.Describe(ch)
+	c.attemptsToSuccess.Describe(ch)
+	c.policyParams.Describe(ch)
+}
+
+// Collect implements prometheus.Collector.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	c.attempts.Collect(ch)
+	c.successes.Collect(ch)
+	c.giveUps.Collect(ch)
+	c.backoffs.Collect(ch)
+	c.attemptsToSuccess.Collect(ch)
+	c.policyParams.Collect(ch)
+}
+
+// SetPolicy exposes the static configuration of the policy used by retry loops with
+// the given name as gauges, so that dashboards can overlay configuration changes with
+// behavior changes across deploys. Each param of each layer of the policy's spec is
+// a separate gauge, where layer 0 is the base policy and subsequent layers are its
+// wrappers from innermost to outermost. A layer without params has a gauge for an
+// empty param with the value 1.
+//
+// It replaces any configuration previously set for the name. It returns an error
+// if the policy can't be described by a spec.
+func (c *Collector) SetPolicy(name string, policy retry.Policy) error {
+	spec, err := retry.SpecOf(policy)
+	if err != nil {
+		return err
+	}
+	c.policyParams.DeletePartialMatch(prometheus.Labels{"retrier": name})
+	layers := append([]retry.Layer{{Type: spec.Type, Params: spec.Params}}, spec.Wrappers...)
+	for i, layer := range layers {
+		idx := strconv.Itoa(i)
+		if len(layer.Params) == 0 {
+			c.policyParams.WithLabelValues(name, idx, layer.Type, "").Set(1)
+		}
+		for param, v := range layer.Params {
+			c.policyParams.WithLabelValues(name, idx, layer.Type, param).Set(v)
+		}
+	}
+	return nil
+}
+
+// Observer returns a retry.Observer that records the metrics of retry loops
+// with the given name, such as the name of the Retrier.
+func (c *Collector) Observer(name string) retry.Observer {
+	return &observer{
+		attempts:          c.attempts.WithLabelValues(name),
+		successes:         c.successes.WithLabelValues(name),
+		giveUps:           c.giveUps.MustCurryWith(prometheus.Labels{"retrier": name}),
+		backoffs:          c.backoffs.WithLabelValues(name),
+		attemptsToSuccess: c.attemptsToSuccess.WithLabelValues(name),
+	}
+}
+
+// Option returns a retry.Option that adds the Observer for the given name to a Retrier.
+func (c *Collector) Option(name string) retry.Option {
+	return retry.WithObserver(c.Observer(name))
+}
+
+type observer struct {
+	attempts          prometheus.Counter
+	successes         prometheus.Counter
+	giveUps           *prometheus.CounterVec
+	backoffs          prometheus.Observer
+	attemptsToSuccess prometheus.Observer
+}
+
+func (o *observer) AttemptStart(ctx context.Context, a retry.Attempt) {
+	o.attempts.Inc()
+}
+
+func (o *observer) AttemptEnd(ctx context.Context, a retry.Attempt, err error) {
+	if err != nil {
+		return
+	}
+	o.successes.Inc()
+	o.attemptsToSuccess.Observe(float64(a.Number))
+}
+
+func (o *observer) Backoff(ctx context.Context, a retry.Attempt, err error, d time.Duration) {
+	o.backoffs.Observe(d.Seconds())
+}
+
+func (o *observer) GiveUp(ctx context.Context, a retry.Attempt, reason retry.Reason, err error) {
+	o.giveUps.WithLabelValues(reason.Label()).Inc()
+}
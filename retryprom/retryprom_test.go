@@ -0,0 +1,97 @@
+// SPDX-License-Identifier: MIT
+//
+// Copyright 2022 Andrew Bursavich. All rights reserved.
+// Use of this source code is governed by The MIT License
+// which can be found in the LICENSE file.
+
+package retryprom_test
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"bursavich.dev/retry"
+	"bursavich.dev/retry/retryprom"
+	"bursavich.dev/retry/retrytest"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var errTest = errors.New("test error")
+
+func TestCollector(t *testing.T) {
+	c := retryprom.NewCollector()
+	reg := prometheus.NewPedanticRegistry()
+	if err := reg.Register(c); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	policy := retry.WithMaxRetries(retry.ConstantBackoff(time.Second), 2)
+	if err := c.SetPolicy("backend", policy); err != nil {
+		t.Fatalf("SetPolicy: %v", err)
+	}
+	r := retry.New(policy, c.Option("backend"), retry.WithClock(retrytest.NewAutoClock(time.Now())))
+	ctx := context.Background()
+	_ = r.Do(ctx, func() error { return errTest })
+	_ = r.Do(ctx, func() error { return retry.NewPermanentError(errTest) })
+	n := 0
+	_ = r.Do(ctx, func() error {
+		if n++; n < 2 {
+			return errTest
+		}
+		return nil
+	})
+
+	got := gather(t, reg)
+	want := []string{
+		`retry_attempts_to_success{retrier="backend"} count=1`,
+		`retry_attempts_total{retrier="backend"} 6`,
+		`retry_backoff_seconds{retrier="backend"} count=3`,
+		`retry_give_ups_total{reason="permanent",retrier="backend"} 1`,
+		`retry_give_ups_total{reason="policy_exhausted",retrier="backend"} 1`,
+		`retry_policy_param{layer="0",param="backoff",retrier="backend",type="constant"} 1`,
+		`retry_policy_param{layer="1",param="limit",retrier="backend",type="max_retries"} 2`,
+		`retry_successes_total{retrier="backend"} 1`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("metrics:\ngot:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+// gather returns a line for each of the metrics gathered from reg, in order.
+// Counters and gauges have their values and histograms have their counts.
+func gather(t *testing.T, reg prometheus.Gatherer) []string {
+	t.Helper()
+	mfs, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("Gather: %v", err)
+	}
+	var lines []string
+	for _, mf := range mfs {
+		for _, m := range mf.GetMetric() {
+			var labels []string
+			for _, l := range m.GetLabel() {
+				labels = append(labels, l.GetName()+`="`+l.GetValue()+`"`)
+			}
+			line := mf.GetName() + "{" + strings.Join(labels, ",") + "}"
+			switch {
+			case m.Counter != nil:
+				line += " " + formatFloat(m.GetCounter().GetValue())
+			case m.Gauge != nil:
+				line += " " + formatFloat(m.GetGauge().GetValue())
+			case m.Histogram != nil:
+				line += " count=" + formatFloat(float64(m.GetHistogram().GetSampleCount()))
+			}
+			lines = append(lines, line)
+		}
+	}
+	sort.Strings(lines)
+	return lines
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
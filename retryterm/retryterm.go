@@ -0,0 +1,162 @@
+// SPDX-License-Identifier: MIT
+//
+// Copyright 2022 Andrew Bursavich. All rights reserved.
+// Use of this source code is governed by The MIT License
+// which can be found in the LICENSE file.
+
+// Package retryterm renders the progress of retry loops as single-line terminal status updates.
+package retryterm
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"bursavich.dev/retry"
+)
+
+const (
+	clearLine = "\r\033[K"
+	red       = "\033[31m"
+	green     = "\033[32m"
+	yellow    = "\033[33m"
+	reset     = "\033[0m"
+)
+
+// An Option configures a Renderer.
+type Option interface {
+	apply(*Renderer)
+}
+
+type optionFunc func(*Renderer)
+
+func (fn optionFunc) apply(r *Renderer) { fn(r) }
+
+// WithMaxAttempts returns an Option that sets the max number of attempts,
+// so that progress is rendered like "attempt 2/5".
+func WithMaxAttempts(n int) Option {
+	return optionFunc(func(r *Renderer) {
+		r.max = n
+	})
+}
+
+// WithColor returns an Option that overrides whether output is colored.
+func WithColor(color bool) Option {
+	return optionFunc(func(r *Renderer) {
+		r.color = color
+	})
+}
+
+// Renderer renders the progress of a retry loop to a writer.
+//
+// If the writer is a terminal, each update replaces the previous line.
+// Otherwise, each update is written on its own line.
+type Renderer struct {
+	w     io.Writer
+	tty   bool
+	color bool
+	max   int
+}
+
+// NewRenderer returns a new Renderer that writes to w.
+//
+// By default, output is colored if w is a terminal, the NO_COLOR environment
+// variable isn't set, and the TERM environment variable isn't "dumb".
+func NewRenderer(w io.Writer, opts ...Option) *Renderer {
+	tty := isTerminal(w)
+	r := &Renderer{
+		w:     w,
+		tty:   tty,
+		color: tty && os.Getenv("NO_COLOR") == "" && os.Getenv("TERM") != "dumb",
+	}
+	for _, o := range opts {
+		o.apply(r)
+	}
+	return r
+}
+
+// Render renders a progress update.
+func (r *Renderer) Render(p retry.Progress) {
+	color := ""
+	if p.Kind != retry.AttemptStarted {
+		color = yellow
+	}
+	r.write(color, Format(p, r.max))
+}
+
+// Done renders the final status of the retry loop and ends the line.
+func (r *Renderer) Done(err error) {
+	if err != nil {
+		r.write(red, "Failed: "+err.Error())
+	} else {
+		r.write(green, "Succeeded")
+	}
+	if r.tty {
+		fmt.Fprintln(r.w)
+	}
+}
+
+// Watch renders the progress of the Handle until its retry loop finishes and returns its error.
+func (r *Renderer) Watch(h *retry.Handle) error {
+	for p := range h.Progress() {
+		r.Render(p)
+	}
+	err := h.Wait()
+	r.Done(err)
+	return err
+}
+
+func (r *Renderer) write(color, line string) {
+	if r.color && color != "" {
+		line = color + line + reset
+	}
+	if r.tty {
+		fmt.Fprint(r.w, clearLine+line)
+	} else {
+		fmt.Fprintln(r.w, line)
+	}
+}
+
+// Format returns a single-line description of the progress update, such as
+// "Retrying in 5s (attempt 2/5)". If maxAttempts isn't positive, it's omitted.
+func Format(p retry.Progress, maxAttempts int) string {
+	attempt := strconv.Itoa(p.Attempt)
+	if maxAttempts > 0 {
+		attempt += "/" + strconv.Itoa(maxAttempts)
+	}
+	switch p.Kind {
+	case retry.AttemptStarted:
+		return "Trying (attempt " + attempt + ")..."
+	case retry.AttemptFailed:
+		return "Attempt " + attempt + " failed: " + errString(p.Err)
+	case retry.BackingOff:
+		return "Retrying in " + formatDuration(p.Backoff) + " (attempt " + attempt + ")"
+	default:
+		return p.Kind.String()
+	}
+}
+
+func errString(err error) string {
+	if err == nil {
+		return "<nil>"
+	}
+	return err.Error()
+}
+
+func formatDuration(d time.Duration) string {
+	if d >= time.Second {
+		return d.Round(100 * time.Millisecond).String()
+	}
+	return d.Round(time.Millisecond).String()
+}
+
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,120 @@
+// SPDX-License-Identifier: MIT
+//
+// Copyright 2022 Andrew Bursavich. All rights reserved.
+// Use of this source code is governed by The MIT License
+// which can be found in the LICENSE file.
+
+package retrytest
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"bursavich.dev/retry"
+)
+
+// Clock is a fake retry.Clock whose time is controlled by the test.
+// It's safe for concurrent use.
+type Clock struct {
+	auto bool
+
+	mu     sync.Mutex
+	now    time.Time
+	timers []*timer
+}
+
+// NewClock returns a new Clock set to the given time. Its time only changes when it's advanced.
+func NewClock(now time.Time) *Clock {
+	return &Clock{now: now}
+}
+
+// NewAutoClock returns a new Clock set to the given time. Whenever a timer is started,
+// the clock advances to the timer's expiration and fires it, so that waiting takes no
+// real time.
+func NewAutoClock(now time.Time) *Clock {
+	return &Clock{now: now, auto: true}
+}
+
+// Now returns the current time of the clock.
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// NewTimer returns a new Timer that fires when the clock is advanced by at least d.
+func (c *Clock) NewTimer(d time.Duration) retry.Timer {
+	t := &timer{c: c, ch: make(chan time.Time, 1)}
+	t.Reset(d)
+	return t
+}
+
+// Advance advances the clock by d and fires the timers that expire.
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.advanceTo(c.now.Add(d))
+}
+
+// advanceTo advances the clock to t and fires the timers that expire, in order.
+// The caller must hold the lock.
+func (c *Clock) advanceTo(t time.Time) {
+	sort.Slice(c.timers, func(i, k int) bool { return c.timers[i].when.Before(c.timers[k].when) })
+	for len(c.timers) > 0 && !c.timers[0].when.After(t) {
+		tm := c.timers[0]
+		c.timers = c.timers[1:]
+		if tm.when.After(c.now) {
+			c.now = tm.when
+		}
+		tm.fire()
+	}
+	if t.After(c.now) {
+		c.now = t
+	}
+}
+
+func (c *Clock) remove(t *timer) bool {
+	for i, tm := range c.timers {
+		if tm == t {
+			c.timers = append(c.timers[:i], c.timers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+type timer struct {
+	c    *Clock
+	ch   chan time.Time
+	when time.Time
+}
+
+func (t *timer) C() <-chan time.Time { return t.ch }
+
+func (t *timer) Stop() bool {
+	t.c.mu.Lock()
+	defer t.c.mu.Unlock()
+	return t.c.remove(t)
+}
+
+func (t *timer) Reset(d time.Duration) bool {
+	t.c.mu.Lock()
+	defer t.c.mu.Unlock()
+	active := t.c.remove(t)
+	t.when = t.c.now.Add(d)
+	t.c.timers = append(t.c.timers, t)
+	if t.c.auto {
+		t.c.advanceTo(t.when)
+	} else {
+		t.c.advanceTo(t.c.now)
+	}
+	return active
+}
+
+func (t *timer) fire() {
+	select {
+	case t.ch <- t.when:
+	default:
+	}
+}
@@ -0,0 +1,94 @@
+// SPDX-License-Identifier: MIT
+//
+// Copyright 2022 Andrew Bursavich. All rights reserved.
+// Use of this source code is governed by The MIT License
+// which can be found in the LICENSE file.
+
+package retrytest
+
+import (
+	"sync"
+	"time"
+
+	"bursavich.dev/retry"
+)
+
+// Decision is a decision made by a Policy.
+type Decision struct {
+	// Err is the error returned by the failed attempt.
+	Err error
+	// Attempt is the number of the retry attempt being decided, starting at 1.
+	Attempt int
+	// Backoff is the backoff duration chosen by the policy.
+	Backoff time.Duration
+	// Retry indicates if the policy allowed a retry.
+	Retry bool
+}
+
+// Recorder is a retry.Policy that wraps a parent Policy and records its decisions.
+// It's safe for concurrent use.
+type Recorder struct {
+	parent retry.Policy
+
+	mu        sync.Mutex
+	decisions []Decision
+}
+
+// NewRecorder returns a new Recorder that wraps the parent Policy.
+func NewRecorder(parent retry.Policy) *Recorder {
+	return &Recorder{parent: parent}
+}
+
+// Next returns the backoff duration to wait before the next attempt
+// and a bool indicating if a retry should be attempted.
+func (r *Recorder) Next(err error, start, now time.Time, attempt int) (time.Duration, bool) {
+	d, ok := r.parent.Next(err, start, now, attempt)
+	r.record(Decision{Err: err, Attempt: attempt, Backoff: d, Retry: ok})
+	return d, ok
+}
+
+// NextState returns the backoff duration to wait before the next attempt
+// and a bool indicating if a retry should be attempted.
+func (r *Recorder) NextState(s *retry.State) (time.Duration, bool) {
+	sp, ok := r.parent.(retry.StatePolicy)
+	if !ok {
+		return r.Next(s.Err, s.Start, s.Now, s.Attempt)
+	}
+	attempt := s.Attempt
+	d, ok := sp.NextState(s)
+	r.record(Decision{Err: s.Err, Attempt: attempt, Backoff: d, Retry: ok})
+	return d, ok
+}
+
+// Decisions returns the decisions that have been recorded.
+func (r *Recorder) Decisions() []Decision {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Decision(nil), r.decisions...)
+}
+
+// Backoffs returns the backoff durations of the recorded decisions that allowed a retry.
+func (r *Recorder) Backoffs() []time.Duration {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var backoffs []time.Duration
+	for _, d := range r.decisions {
+		if d.Retry {
+			backoffs = append(backoffs, d.Backoff)
+		}
+	}
+	return backoffs
+}
+
+// Reset discards the recorded decisions.
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.decisions = nil
+}
+
+func (r *Recorder) record(d Decision) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.decisions = append(r.decisions, d)
+}
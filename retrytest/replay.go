@@ -0,0 +1,275 @@
+// SPDX-License-Identifier: MIT
+//
+// Copyright 2022 Andrew Bursavich. All rights reserved.
+// Use of this source code is governed by The MIT License
+// which can be found in the LICENSE file.
+
+package retrytest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"bursavich.dev/retry"
+)
+
+// Kinds of Events.
+const (
+	EventAttemptStart = "attempt_start"
+	EventAttemptEnd   = "attempt_end"
+	EventBackoff      = "backoff"
+	EventGiveUp       = "give_up"
+)
+
+// Event is an event of a retry loop that's exported as JSON by an Exporter.
+type Event struct {
+	// Kind is the kind of the event.
+	Kind string `json:"kind"`
+	// Time is the time at which the event occurred.
+	Time time.Time `json:"time"`
+	// LoopStart is the time at which the retry loop started.
+	LoopStart time.Time `json:"loop_start"`
+	// Loop identifies the retry loop, along with LoopStart.
+	Loop uint64 `json:"loop,omitempty"`
+	// Attempt is the number of the attempt, starting at 1.
+	Attempt int `json:"attempt"`
+	// Error is the error returned by the attempt, if any.
+	Error string `json:"error,omitempty"`
+	// Backoff is the backoff after the attempt, for backoff events.
+	Backoff time.Duration `json:"backoff,omitempty"`
+	// Reason is the reason the loop stopped, for give-up events.
+	Reason string `json:"reason,omitempty"`
+}
+
+// Exporter is a retry.Observer that writes the events of retry loops to a writer
+// as JSON, one event per line, such as for exporting them from production to be
+// replayed against a candidate policy. It's safe for concurrent use.
+type Exporter struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// NewExporter returns a new Exporter that writes to w.
+func NewExporter(w io.Writer) *Exporter {
+	return &Exporter{enc: json.NewEncoder(w)}
+}
+
+// AttemptStart implements retry.Observer.
+func (e *Exporter) AttemptStart(ctx context.Context, a retry.Attempt) {
+	e.write(Event{Kind: EventAttemptStart, Time: a.Start, LoopStart: a.LoopStart, Loop: a.Loop, Attempt: a.Number})
+}
+
+// AttemptEnd implements retry.Observer.
+func (e *Exporter) AttemptEnd(ctx context.Context, a retry.Attempt, err error) {
+	e.write(Event{Kind: EventAttemptEnd, Time: a.End, LoopStart: a.LoopStart, Loop: a.Loop, Attempt: a.Number, Error: errString(err)})
+}
+
+// Backoff implements retry.Observer.
+func (e *Exporter) Backoff(ctx context.Context, a retry.Attempt, err error, d time.Duration) {
+	e.write(Event{Kind: EventBackoff, Time: a.BackoffStart, LoopStart: a.LoopStart, Loop: a.Loop, Attempt: a.Number, Error: errString(err), Backoff: d})
+}
+
+// GiveUp implements retry.Observer.
+func (e *Exporter) GiveUp(ctx context.Context, a retry.Attempt, reason retry.Reason, err error) {
+	e.write(Event{Kind: EventGiveUp, Time: a.Latest(), LoopStart: a.LoopStart, Loop: a.Loop, Attempt: a.Number, Error: errString(err), Reason: reason.String()})
+}
+
+func (e *Exporter) write(ev Event) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	_ = e.enc.Encode(ev) // Best effort.
+}
+
+func errString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+// ReadEvents reads the events written by an Exporter from r.
+func ReadEvents(r io.Reader) ([]Event, error) {
+	var events []Event
+	dec := json.NewDecoder(r)
+	for {
+		var ev Event
+		if err := dec.Decode(&ev); err == io.EOF {
+			return events, nil
+		} else if err != nil {
+			return events, err
+		}
+		events = append(events, ev)
+	}
+}
+
+// Result is the result of a retry loop.
+type Result struct {
+	// Attempts is the number of attempts made.
+	Attempts int
+	// Elapsed is the duration from the start of the loop to the end of its last attempt.
+	Elapsed time.Duration
+	// Succeeded indicates if an attempt succeeded.
+	Succeeded bool
+}
+
+// Outcome compares the result of a retry loop in production with
+// the result of replaying it against a candidate policy.
+type Outcome struct {
+	// LoopStart is the time at which the retry loop started.
+	LoopStart time.Time
+	// Loop identifies the retry loop, along with LoopStart.
+	Loop uint64
+	// Production is the result of the loop in production.
+	Production Result
+	// Candidate is the result of the loop with the candidate policy.
+	Candidate Result
+}
+
+// maxReplayAttempts is the number of attempts after which a replayed loop is stopped.
+const maxReplayAttempts = 1000
+
+// Replay replays the retry loops of the events against the candidate policy in virtual
+// time and returns their outcomes, ordered by the start of the loops, showing how the
+// policy would have behaved during an incident.
+//
+// The dependency is modeled by the production timeline of each loop: an attempt made at
+// a given time fails with the error and takes the duration of the latest production attempt
+// that started by then, or of the first one, and succeeds once the production loop has
+// succeeded. If the production loop gave up, the dependency is assumed to keep failing.
+// Replayed loops are stopped after 1000 attempts.
+func Replay(events []Event, policy retry.Policy, opts ...retry.Option) []Outcome {
+	loops := make(map[loopKey]*timeline)
+	for _, ev := range events {
+		key := loopKey{start: ev.LoopStart.UnixNano(), id: ev.Loop}
+		tl, ok := loops[key]
+		if !ok {
+			tl = &timeline{start: ev.LoopStart, id: ev.Loop}
+			loops[key] = tl
+		}
+		tl.add(ev)
+	}
+	outcomes := make([]Outcome, 0, len(loops))
+	for _, tl := range loops {
+		if _, ok := tl.at(tl.start); !ok {
+			continue // No attempts ended.
+		}
+		outcomes = append(outcomes, Outcome{
+			LoopStart:  tl.start,
+			Loop:       tl.id,
+			Production: tl.result(),
+			Candidate:  tl.replay(policy, opts),
+		})
+	}
+	sort.Slice(outcomes, func(i, k int) bool {
+		if !outcomes[i].LoopStart.Equal(outcomes[k].LoopStart) {
+			return outcomes[i].LoopStart.Before(outcomes[k].LoopStart)
+		}
+		return outcomes[i].Loop < outcomes[k].Loop
+	})
+	return outcomes
+}
+
+// loopKey identifies a retry loop. Its start is in Unix nanoseconds,
+// so that it doesn't depend on the time's location.
+type loopKey struct {
+	start int64
+	id    uint64
+}
+
+// timeline is the production timeline of a retry loop.
+type timeline struct {
+	start     time.Time
+	id        uint64
+	attempts  []attempt // ordered by number
+	permanent bool      // whether the loop gave up because of a permanent error
+}
+
+type attempt struct {
+	number     int
+	start, end time.Time
+	err        string
+	ended      bool
+}
+
+func (tl *timeline) get(number int) *attempt {
+	for i := range tl.attempts {
+		if tl.attempts[i].number == number {
+			return &tl.attempts[i]
+		}
+	}
+	tl.attempts = append(tl.attempts, attempt{number: number})
+	sort.Slice(tl.attempts, func(i, k int) bool { return tl.attempts[i].number < tl.attempts[k].number })
+	return tl.get(number)
+}
+
+func (tl *timeline) add(ev Event) {
+	switch ev.Kind {
+	case EventAttemptStart:
+		tl.get(ev.Attempt).start = ev.Time
+	case EventAttemptEnd:
+		a := tl.get(ev.Attempt)
+		a.end, a.err, a.ended = ev.Time, ev.Error, true
+	case EventGiveUp:
+		tl.permanent = ev.Reason == retry.ReasonPermanent.String()
+	}
+}
+
+func (tl *timeline) result() Result {
+	var r Result
+	for _, a := range tl.attempts {
+		r.Attempts = max(r.Attempts, a.number)
+		if a.ended {
+			r.Elapsed = max(r.Elapsed, a.end.Sub(tl.start))
+			r.Succeeded = r.Succeeded || a.err == ""
+		}
+	}
+	return r
+}
+
+// at returns the production attempt that models an attempt made at t.
+func (tl *timeline) at(t time.Time) (attempt, bool) {
+	var (
+		found attempt
+		ok    bool
+	)
+	for _, a := range tl.attempts {
+		if !a.ended {
+			continue
+		}
+		if ok && a.start.After(t) {
+			break
+		}
+		found, ok = a, true
+	}
+	return found, ok
+}
+
+func (tl *timeline) replay(policy retry.Policy, opts []retry.Option) Result {
+	clock := NewAutoClock(tl.start)
+	opts = append(opts[:len(opts):len(opts)], retry.WithClock(clock))
+	last := tl.attempts[len(tl.attempts)-1].number
+	var r Result
+	_ = retry.New(policy, opts...).Do(context.Background(), func() error {
+		r.Attempts++
+		a, _ := tl.at(clock.Now())
+		if a.end.After(a.start) {
+			clock.Advance(a.end.Sub(a.start))
+		}
+		r.Elapsed = clock.Now().Sub(tl.start)
+		if a.err == "" {
+			r.Succeeded = true
+			return nil
+		}
+		err := errors.New(a.err)
+		if r.Attempts >= maxReplayAttempts || (tl.permanent && a.number == last) {
+			return retry.NewPermanentError(err)
+		}
+		return err
+	})
+	return r
+}
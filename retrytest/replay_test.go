@@ -0,0 +1,69 @@
+// SPDX-License-Identifier: MIT
+//
+// Copyright 2022 Andrew Bursavich. All rights reserved.
+// Use of this source code is governed by The MIT License
+// which can be found in the LICENSE file.
+
+package retrytest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"bursavich.dev/retry"
+)
+
+func TestReplaySimultaneousLoops(t *testing.T) {
+	errTest := errors.New("test error")
+	start := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	var buf bytes.Buffer
+	exp := NewExporter(&buf)
+
+	// Two loops start at the same time: one succeeds on its second attempt
+	// and the other gives up after three attempts.
+	for _, succeedAt := range []int{2, 0} {
+		r := retry.New(
+			retry.WithMaxRetries(retry.ConstantBackoff(time.Second), 2),
+			retry.WithClock(NewAutoClock(start)),
+			retry.WithObserver(exp),
+		)
+		n := 0
+		_ = r.Do(context.Background(), func() error {
+			if n++; n == succeedAt {
+				return nil
+			}
+			return errTest
+		})
+	}
+
+	events, err := ReadEvents(&buf)
+	if err != nil {
+		t.Fatalf("ReadEvents: %v", err)
+	}
+	for _, ev := range events {
+		if ev.Kind == EventGiveUp {
+			if want := start.Add(2 * time.Second); !ev.Time.Equal(want) {
+				t.Errorf("give-up time: got %v; want %v", ev.Time, want)
+			}
+		}
+	}
+	outcomes := Replay(events, retry.WithMaxRetries(retry.ConstantBackoff(time.Second), 2))
+	if len(outcomes) != 2 {
+		t.Fatalf("outcomes: got %d; want 2", len(outcomes))
+	}
+	want := []Result{
+		{Attempts: 2, Elapsed: time.Second, Succeeded: true},
+		{Attempts: 3, Elapsed: 2 * time.Second},
+	}
+	for i, o := range outcomes {
+		if o.Production != want[i] {
+			t.Errorf("outcome %d: got production %+v; want %+v", i, o.Production, want[i])
+		}
+		if o.Candidate != want[i] {
+			t.Errorf("outcome %d: got candidate %+v; want %+v", i, o.Candidate, want[i])
+		}
+	}
+}
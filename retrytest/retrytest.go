@@ -0,0 +1,63 @@
+// SPDX-License-Identifier: MIT
+//
+// Copyright 2022 Andrew Bursavich. All rights reserved.
+// Use of this source code is governed by The MIT License
+// which can be found in the LICENSE file.
+
+// Package retrytest provides utilities for testing code that retries.
+package retrytest
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"bursavich.dev/retry"
+	"bursavich.dev/retry/internal/hooks"
+)
+
+// RequireNoSleep fails the test if any retry loop sleeps before the test completes.
+// It catches accidentally unmocked retry paths that silently make test suites slow.
+//
+// Retriers, Tickers, and Senders sleep if they use the system clock and wait for a
+// retry, including when a Retrier waits for its initial delay, for coalesced backoffs,
+// or for a health gate to spread out its release over the ramp window. Timers that
+// aren't retry backoffs, such as those of a PressureReporter or of a Sender's rate
+// limit, aren't reported.
+//
+// It applies to every retry loop in the process, so it shouldn't be used
+// by tests that run in parallel with tests that are expected to sleep.
+func RequireNoSleep(t testing.TB) {
+	t.Helper()
+	var (
+		mu    sync.Mutex
+		count int
+		first time.Duration
+	)
+	restore := hooks.SetSleep(func(d time.Duration) {
+		mu.Lock()
+		defer mu.Unlock()
+		if count == 0 {
+			first = d
+		}
+		count++
+	})
+	t.Cleanup(func() {
+		restore()
+		mu.Lock()
+		defer mu.Unlock()
+		if count > 0 {
+			t.Errorf("retrytest: retry loops slept %d time(s), first for %v", count, first)
+		}
+	})
+}
+
+// Do executes the retriable function according to the given policy and options,
+// like retry.Do, but with a Clock that advances automatically so that it runs
+// to completion without real sleeping. Context deadlines are compared against
+// the clock's time, which starts at the current time.
+func Do(ctx context.Context, policy retry.Policy, fn func() error, opts ...retry.Option) error {
+	opts = append(opts[:len(opts):len(opts)], retry.WithClock(NewAutoClock(time.Now())))
+	return retry.New(policy, opts...).Do(ctx, fn)
+}
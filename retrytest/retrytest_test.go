@@ -0,0 +1,75 @@
+// SPDX-License-Identifier: MIT
+//
+// Copyright 2022 Andrew Bursavich. All rights reserved.
+// Use of this source code is governed by The MIT License
+// which can be found in the LICENSE file.
+
+package retrytest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"bursavich.dev/retry"
+)
+
+// recordingTB records the failures and cleanups of a test.
+type recordingTB struct {
+	testing.TB
+	failed   bool
+	cleanups []func()
+}
+
+func (tb *recordingTB) Helper()               {}
+func (tb *recordingTB) Errorf(string, ...any) { tb.failed = true }
+func (tb *recordingTB) Cleanup(fn func())     { tb.cleanups = append(tb.cleanups, fn) }
+func (tb *recordingTB) cleanup() {
+	for _, fn := range tb.cleanups {
+		fn()
+	}
+}
+
+func TestRequireNoSleep(t *testing.T) {
+	errTest := errors.New("test error")
+	tests := []struct {
+		name  string
+		sleep bool
+		run   func()
+	}{
+		{
+			name: "fake clock",
+			run: func() {
+				_ = Do(context.Background(), retry.WithMaxRetries(retry.ConstantBackoff(time.Hour), 2), func() error { return errTest })
+			},
+		},
+		{
+			name:  "system clock",
+			sleep: true,
+			run: func() {
+				_ = retry.New(retry.WithMaxRetries(retry.ConstantBackoff(time.Millisecond), 1)).Do(context.Background(), func() error { return errTest })
+			},
+		},
+		{
+			name:  "ticker",
+			sleep: true,
+			run: func() {
+				tk := retry.NewTicker(retry.ConstantBackoff(time.Hour))
+				defer tk.Stop()
+				tk.Fail(errTest)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tb := &recordingTB{TB: t}
+			RequireNoSleep(tb)
+			tt.run()
+			tb.cleanup()
+			if tb.failed != tt.sleep {
+				t.Errorf("failed: got %v; want %v", tb.failed, tt.sleep)
+			}
+		})
+	}
+}
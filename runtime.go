@@ -0,0 +1,71 @@
+// SPDX-License-Identifier: MIT
+//
+// Copyright 2022 Andrew Bursavich. All rights reserved.
+// Use of this source code is governed by The MIT License
+// which can be found in the LICENSE file.
+
+package retry
+
+import "runtime/metrics"
+
+// RuntimeStats contains the changes in runtime statistics across an attempt.
+//
+// The statistics are process-wide, so they include the allocations and goroutines
+// of everything else running concurrently. A Goroutines count that grows with every
+// failed attempt of an otherwise quiet process is a sign that the function leaks.
+type RuntimeStats struct {
+	// Attempt is the number of the attempt, starting at 1.
+	Attempt int
+	// Allocs is the number of heap objects allocated during the attempt.
+	Allocs uint64
+	// AllocBytes is the number of heap bytes allocated during the attempt.
+	AllocBytes uint64
+	// Goroutines is the change in the number of live goroutines across the attempt.
+	Goroutines int64
+}
+
+// WithRuntimeStats returns an Option that records the changes in runtime statistics
+// across each attempt in the Report returned by DoReport, such as to identify functions
+// that leak goroutines or memory on each failed attempt. It's a diagnostic mode:
+// reading the statistics isn't free, so it shouldn't be enabled by default.
+func WithRuntimeStats() Option {
+	return optionFunc(func(r *Retrier) {
+		r.runtimeStats = true
+	})
+}
+
+var runtimeMetrics = [...]string{
+	"/gc/heap/allocs:objects",
+	"/gc/heap/allocs:bytes",
+	"/sched/goroutines:goroutines",
+}
+
+// runtimeSnapshot is a snapshot of runtime statistics.
+type runtimeSnapshot [len(runtimeMetrics)]metrics.Sample
+
+func readRuntime() *runtimeSnapshot {
+	var s runtimeSnapshot
+	for i, name := range runtimeMetrics {
+		s[i].Name = name
+	}
+	metrics.Read(s[:])
+	return &s
+}
+
+// since returns the changes in runtime statistics since the snapshot.
+func (s *runtimeSnapshot) since(attempt int) RuntimeStats {
+	now := readRuntime()
+	return RuntimeStats{
+		Attempt:    attempt,
+		Allocs:     sampleUint64(now[0]) - sampleUint64(s[0]),
+		AllocBytes: sampleUint64(now[1]) - sampleUint64(s[1]),
+		Goroutines: int64(sampleUint64(now[2])) - int64(sampleUint64(s[2])),
+	}
+}
+
+func sampleUint64(s metrics.Sample) uint64 {
+	if s.Value.Kind() != metrics.KindUint64 {
+		return 0
+	}
+	return s.Value.Uint64()
+}
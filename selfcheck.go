@@ -0,0 +1,143 @@
+// SPDX-License-Identifier: MIT
+//
+// Copyright 2022 Andrew Bursavich. All rights reserved.
+// Use of this source code is governed by The MIT License
+// which can be found in the LICENSE file.
+
+package retry
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"time"
+)
+
+// A SelfCheckOption configures a SelfCheck.
+type SelfCheckOption interface {
+	apply(*selfCheck)
+}
+
+type selfCheckOptionFunc func(*selfCheck)
+
+func (fn selfCheckOptionFunc) apply(c *selfCheck) { fn(c) }
+
+// WithCheckAttempts returns a SelfCheckOption that sets the maximum number of
+// retry attempts that are simulated. The default is 20.
+func WithCheckAttempts(n int) SelfCheckOption {
+	return selfCheckOptionFunc(func(c *selfCheck) {
+		c.attempts = max(n, 1)
+	})
+}
+
+// WithCheckError returns a SelfCheckOption that sets the error with which
+// the simulated attempts fail. The default is nil.
+func WithCheckError(err error) SelfCheckOption {
+	return selfCheckOptionFunc(func(c *selfCheck) {
+		c.err = err
+	})
+}
+
+type selfCheck struct {
+	attempts int
+	err      error
+}
+
+// SelfCheckReport is the result of a SelfCheck.
+type SelfCheckReport struct {
+	// Policy is a description of the policy.
+	Policy string
+	// Backoffs are the simulated backoffs, in order.
+	Backoffs []time.Duration
+	// Elapsed is the total simulated duration spent backing off.
+	Elapsed time.Duration
+	// Stopped indicates if the policy stopped retrying within the simulated attempts.
+	Stopped bool
+	// Warnings are the warnings returned by Validate.
+	Warnings []string
+	// Violations are the invariants that were violated by the simulated schedule.
+	Violations []string
+}
+
+// OK reports whether the simulated schedule didn't violate any invariants.
+func (r *SelfCheckReport) OK() bool {
+	return len(r.Violations) == 0
+}
+
+// String returns a textual representation of the report,
+// such as for rendering in an admin endpoint.
+func (r *SelfCheckReport) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "policy: %s\n", r.Policy)
+	var total time.Duration
+	for i, d := range r.Backoffs {
+		total += d
+		fmt.Fprintf(&b, "attempt %d: retry after %v (total %v)\n", i+1, d, total)
+	}
+	if r.Stopped {
+		fmt.Fprintf(&b, "attempt %d: don't retry\n", len(r.Backoffs)+1)
+	} else {
+		fmt.Fprintf(&b, "still retrying after %d attempts\n", len(r.Backoffs))
+	}
+	for _, w := range r.Warnings {
+		fmt.Fprintf(&b, "warning: %s\n", w)
+	}
+	for _, v := range r.Violations {
+		fmt.Fprintf(&b, "violation: %s\n", v)
+	}
+	if r.OK() {
+		b.WriteString("ok")
+	} else {
+		b.WriteString("failed")
+	}
+	return b.String()
+}
+
+// SelfCheck runs a quick simulation of the policy's schedule and validates its
+// invariants, such that backoffs are never negative and are within the bounds
+// computed by Table, so that operators can verify retry configuration at runtime,
+// such as from an admin or health endpoint.
+//
+// Attempts are assumed to take no time and time isn't actually spent backing off.
+// Jitter is drawn from a fixed seed, so the simulation is reproducible.
+func SelfCheck(policy Policy, opts ...SelfCheckOption) *SelfCheckReport {
+	c := selfCheck{attempts: 20}
+	for _, opt := range opts {
+		opt.apply(&c)
+	}
+	r := &SelfCheckReport{
+		Policy:   policyName(policy),
+		Warnings: Validate(policy),
+	}
+	start := time.Now()
+	s := State{
+		Err:   c.err,
+		Start: start,
+		rand:  &lockedRand{r: rand.New(rand.NewPCG(0, 0))},
+	}
+	for attempt := 1; attempt <= c.attempts; attempt++ {
+		s.Now = start.Add(r.Elapsed)
+		s.Attempt++
+		d, ok := next(policy, &s)
+		if s.Attempt != attempt {
+			// The policy skipped attempts.
+			attempt = s.Attempt
+		}
+		min, max, allowed := bounds(policy, attempt, r.Elapsed)
+		if !ok {
+			r.Stopped = true
+			break
+		}
+		switch {
+		case d < 0:
+			r.Violations = append(r.Violations, fmt.Sprintf("attempt %d: negative backoff %v", attempt, d))
+		case !allowed:
+			r.Violations = append(r.Violations, fmt.Sprintf("attempt %d: retried after %v, but its bounds don't allow a retry", attempt, d))
+		case d < min || d > max:
+			r.Violations = append(r.Violations, fmt.Sprintf("attempt %d: backoff %v is outside of its bounds [%v, %v]", attempt, d, min, max))
+		}
+		r.Backoffs = append(r.Backoffs, d)
+		r.Elapsed += d
+	}
+	return r
+}
@@ -0,0 +1,206 @@
+// SPDX-License-Identifier: MIT
+//
+// Copyright 2022 Andrew Bursavich. All rights reserved.
+// Use of this source code is governed by The MIT License
+// which can be found in the LICENSE file.
+
+package retry
+
+import (
+	"container/heap"
+	"context"
+	"sync"
+	"time"
+
+	"bursavich.dev/retry/internal/hooks"
+)
+
+// A SenderOption configures a Sender.
+type SenderOption[T any] interface {
+	apply(*Sender[T])
+}
+
+type senderOptionFunc[T any] func(*Sender[T])
+
+func (fn senderOptionFunc[T]) apply(s *Sender[T]) { fn(s) }
+
+// WithDropHandler returns a SenderOption that calls handle with each item that's dropped
+// because its retries were exhausted or it failed with a permanent error.
+func WithDropHandler[T any](handle func(ctx context.Context, item T, err error)) SenderOption[T] {
+	return senderOptionFunc[T](func(s *Sender[T]) {
+		s.drop = handle
+	})
+}
+
+// WithSenderClock returns a SenderOption that sets the Clock used by the Sender.
+func WithSenderClock[T any](c Clock) SenderOption[T] {
+	return senderOptionFunc[T](func(s *Sender[T]) {
+		if c != nil {
+			s.clock = c
+		}
+	})
+}
+
+// Sender is a leaky bucket that sends items, such as telemetry or events, at a fixed rate.
+// Items that fail to be sent are re-queued after the Policy's backoff, while items that are
+// ready are sent at most once per interval. It's a single primitive for resilient emission
+// that neither floods the destination after an outage nor drops items during brief failures.
+type Sender[T any] struct {
+	policy   Policy
+	interval time.Duration
+	capacity int
+	send     func(context.Context, T) error
+	drop     func(context.Context, T, error)
+	clock    Clock
+
+	mu      sync.Mutex
+	ready   []*pendingItem[T] // in order of readiness
+	delayed pendingHeap[T]
+}
+
+type pendingItem[T any] struct {
+	item  T
+	state State
+	at    time.Time // when it's ready to be sent
+}
+
+// Defaults of a Sender.
+const (
+	defaultSenderInterval = 100 * time.Millisecond
+	defaultSenderCapacity = 1000
+)
+
+// NewSender returns a new Sender that calls send for at most one item per interval
+// and holds at most capacity items that are waiting to be sent. If interval isn't
+// positive, it defaults to 100ms. If capacity isn't positive, it defaults to 1000.
+func NewSender[T any](policy Policy, interval time.Duration, capacity int, send func(ctx context.Context, item T) error, opts ...SenderOption[T]) *Sender[T] {
+	if interval <= 0 {
+		interval = defaultSenderInterval
+	}
+	if capacity <= 0 {
+		capacity = defaultSenderCapacity
+	}
+	s := &Sender[T]{
+		policy:   policy,
+		interval: interval,
+		capacity: capacity,
+		send:     send,
+		clock:    systemClock{},
+	}
+	for _, o := range opts {
+		o.apply(s)
+	}
+	return s
+}
+
+// Enqueue queues the item to be sent. It returns false if the Sender is full.
+func (s *Sender[T]) Enqueue(item T) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.ready)+len(s.delayed) >= s.capacity {
+		return false
+	}
+	s.ready = append(s.ready, &pendingItem[T]{item: item})
+	return true
+}
+
+// Len returns the number of items that are waiting to be sent.
+func (s *Sender[T]) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.ready) + len(s.delayed)
+}
+
+// Run sends items until ctx is done and then returns the context's error.
+// Items that are still waiting to be sent remain queued. A tightened context,
+// as returned by WithTightening, caps the attempts and backoffs of each item.
+func (s *Sender[T]) Run(ctx context.Context) error {
+	t := s.clock.NewTimer(s.interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case now := <-t.C():
+			if p := s.pop(now); p != nil {
+				s.sendItem(ctx, p)
+			}
+			t.Reset(s.interval)
+		}
+	}
+}
+
+// pop returns the next item that's ready to be sent, or nil if there isn't one.
+func (s *Sender[T]) pop(now time.Time) *pendingItem[T] {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for len(s.delayed) > 0 && !s.delayed[0].at.After(now) {
+		s.ready = append(s.ready, heap.Pop(&s.delayed).(*pendingItem[T]))
+	}
+	if len(s.ready) == 0 {
+		return nil
+	}
+	p := s.ready[0]
+	s.ready[0] = nil
+	s.ready = s.ready[1:]
+	return p
+}
+
+func (s *Sender[T]) sendItem(ctx context.Context, p *pendingItem[T]) {
+	now := s.clock.Now()
+	if p.state.Start.IsZero() {
+		p.state.Start = now
+	}
+	err := s.send(ctx, p.item)
+	if err == nil {
+		return
+	}
+	if isPermErr(err) {
+		s.dropItem(ctx, p, err)
+		return
+	}
+	p.state.Err, p.state.Now = err, s.clock.Now()
+	p.state.Attempt++
+	tight := tighteningFrom(ctx)
+	backoff, ok := next(s.policy, &p.state)
+	if !ok || !tight.allows(p.state.Attempt) {
+		s.dropItem(ctx, p, err)
+		return
+	}
+	p.at = p.state.Now.Add(tight.cap(backoff))
+	if notBefore, ok := NotBefore(err); ok && notBefore.After(p.at) {
+		if tight.cap(notBefore.Sub(p.state.Now)) < notBefore.Sub(p.state.Now) {
+			s.dropItem(ctx, p, err)
+			return
+		}
+		p.at = notBefore
+	}
+	if _, ok := s.clock.(systemClock); ok && p.at.After(p.state.Now) {
+		hooks.Sleep(p.at.Sub(p.state.Now))
+	}
+	s.mu.Lock()
+	heap.Push(&s.delayed, p)
+	s.mu.Unlock()
+}
+
+func (s *Sender[T]) dropItem(ctx context.Context, p *pendingItem[T], err error) {
+	if s.drop != nil {
+		s.drop(ctx, p.item, err)
+	}
+}
+
+type pendingHeap[T any] []*pendingItem[T]
+
+func (h pendingHeap[T]) Len() int           { return len(h) }
+func (h pendingHeap[T]) Less(i, j int) bool { return h[i].at.Before(h[j].at) }
+func (h pendingHeap[T]) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *pendingHeap[T]) Push(x any)        { *h = append(*h, x.(*pendingItem[T])) }
+
+func (h *pendingHeap[T]) Pop() any {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	old[n-1] = nil
+	*h = old[:n-1]
+	return x
+}
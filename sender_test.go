@@ -0,0 +1,133 @@
+// SPDX-License-Identifier: MIT
+//
+// Copyright 2022 Andrew Bursavich. All rights reserved.
+// Use of this source code is governed by The MIT License
+// which can be found in the LICENSE file.
+
+package retry_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"bursavich.dev/retry"
+	"bursavich.dev/retry/retrytest"
+)
+
+func TestSender(t *testing.T) {
+	start := time.Now()
+	clock := retrytest.NewAutoClock(start)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var (
+		sent    []string
+		dropped []string
+		fails   = map[string]int{"a": 1, "b": 0, "c": 100}
+	)
+	s := retry.NewSender(
+		retry.WithMaxRetries(retry.ConstantBackoff(5*time.Second), 1),
+		time.Second,
+		10,
+		func(ctx context.Context, item string) error {
+			sent = append(sent, fmt.Sprintf("%s@%v", item, clock.Now().Sub(start)))
+			if fails[item] > 0 {
+				fails[item]--
+				return errTest
+			}
+			return nil
+		},
+		retry.WithSenderClock[string](clock),
+		retry.WithDropHandler(func(ctx context.Context, item string, err error) {
+			dropped = append(dropped, item)
+			cancel()
+		}),
+	)
+	for _, item := range []string{"a", "b", "c"} {
+		if !s.Enqueue(item) {
+			t.Fatalf("Enqueue(%q): queue is full", item)
+		}
+	}
+	if got := s.Len(); got != 3 {
+		t.Errorf("Len: got %d; want 3", got)
+	}
+
+	if err := s.Run(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("Run: got %v; want %v", err, context.Canceled)
+	}
+	// Failed items wait for their backoff, while ready items are sent once per interval.
+	want := []string{"a@1s", "b@2s", "c@3s", "a@6s", "c@8s"}
+	if !slices.Equal(sent, want) {
+		t.Errorf("sent: got %v; want %v", sent, want)
+	}
+	if want := []string{"c"}; !slices.Equal(dropped, want) {
+		t.Errorf("dropped: got %v; want %v", dropped, want)
+	}
+	if got := s.Len(); got != 0 {
+		t.Errorf("Len: got %d; want 0", got)
+	}
+}
+
+func TestSenderPermanentError(t *testing.T) {
+	clock := retrytest.NewAutoClock(time.Now())
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var dropped []error
+	s := retry.NewSender(
+		retry.ConstantBackoff(time.Second),
+		time.Second,
+		1,
+		func(ctx context.Context, item int) error {
+			return retry.NewPermanentError(errTest)
+		},
+		retry.WithSenderClock[int](clock),
+		retry.WithDropHandler(func(ctx context.Context, item int, err error) {
+			dropped = append(dropped, err)
+			cancel()
+		}),
+	)
+	s.Enqueue(1)
+	_ = s.Run(ctx)
+	if len(dropped) != 1 || !errors.Is(dropped[0], errTest) {
+		t.Errorf("dropped: got %v; want [%v]", dropped, errTest)
+	}
+}
+
+func TestSenderDefaults(t *testing.T) {
+	start := time.Now()
+	clock := retrytest.NewAutoClock(start)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var at time.Duration
+	s := retry.NewSender(
+		retry.ConstantBackoff(time.Second),
+		0,
+		0,
+		func(ctx context.Context, item int) error {
+			if at == 0 {
+				at = clock.Now().Sub(start)
+			}
+			cancel()
+			return nil
+		},
+		retry.WithSenderClock[int](clock),
+	)
+	for i := range 1000 {
+		if !s.Enqueue(i) {
+			t.Fatalf("Enqueue(%d): queue is full", i)
+		}
+	}
+	if s.Enqueue(1000) {
+		t.Error("Enqueue: got true beyond the default capacity; want false")
+	}
+	_ = s.Run(ctx)
+	if want := 100 * time.Millisecond; at != want {
+		t.Errorf("first send: got %v; want %v", at, want)
+	}
+}
@@ -0,0 +1,38 @@
+// SPDX-License-Identifier: MIT
+//
+// Copyright 2022 Andrew Bursavich. All rights reserved.
+// Use of this source code is governed by The MIT License
+// which can be found in the LICENSE file.
+
+package retry
+
+import "math"
+
+// Shrinker computes sizes that shrink across attempts, such as the batch size of a request
+// that's retried because it exceeded a payload limit.
+type Shrinker struct {
+	initial int
+	min     int
+	factor  float64
+}
+
+// Shrink returns a Shrinker whose size starts at initial and is scaled by the factor for
+// each successive attempt until it's floored at min. The factor should be in (0, 1).
+//
+//	s := retry.Shrink(1000, 10, 0.5)
+//	err := retry.DoCtx(ctx, policy, func(ctx context.Context) error {
+//		a, _ := retry.AttemptFromContext(ctx)
+//		return send(ctx, items[:min(len(items), s.SizeFor(a.Number))])
+//	})
+func Shrink(initial, min int, factor float64) Shrinker {
+	return Shrinker{initial: initial, min: min, factor: factor}
+}
+
+// SizeFor returns the size for the given attempt, starting at 1.
+func (s Shrinker) SizeFor(attempt int) int {
+	size := float64(s.initial) * math.Pow(s.factor, float64(max(attempt, 1)-1))
+	if size < float64(s.min) {
+		return s.min
+	}
+	return int(size)
+}
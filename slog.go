@@ -0,0 +1,64 @@
+// SPDX-License-Identifier: MIT
+//
+// Copyright 2022 Andrew Bursavich. All rights reserved.
+// Use of this source code is governed by The MIT License
+// which can be found in the LICENSE file.
+
+package retry
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// WithSlog returns an Option that logs the Retrier's decisions to logger at the given level.
+// A record is emitted for each retry with the attempt, error, backoff, and elapsed duration,
+// when the retry loop gives up, and when an attempt succeeds after retrying.
+// If logger is nil, the default logger is used, as returned by slog.Default.
+func WithSlog(logger *slog.Logger, level slog.Level) Option {
+	return WithObserver(&slogObserver{logger: logger, level: level})
+}
+
+type slogObserver struct {
+	logger *slog.Logger
+	level  slog.Level
+}
+
+// log returns the logger, which is resolved when it's used so that it follows slog.SetDefault.
+func (o *slogObserver) log() *slog.Logger {
+	if o.logger == nil {
+		return slog.Default()
+	}
+	return o.logger
+}
+
+func (o *slogObserver) AttemptStart(ctx context.Context, a Attempt) {}
+
+func (o *slogObserver) AttemptEnd(ctx context.Context, a Attempt, err error) {
+	if err != nil || a.Number == 1 {
+		return
+	}
+	o.log().LogAttrs(ctx, o.level, "retry succeeded",
+		slog.Int("attempt", a.Number),
+		slog.Duration("elapsed", a.End.Sub(a.LoopStart)),
+	)
+}
+
+func (o *slogObserver) Backoff(ctx context.Context, a Attempt, err error, d time.Duration) {
+	o.log().LogAttrs(ctx, o.level, "retrying",
+		slog.Int("attempt", a.Number),
+		slog.Any("error", err),
+		slog.Duration("backoff", d),
+		slog.Duration("elapsed", a.BackoffStart.Sub(a.LoopStart)),
+	)
+}
+
+func (o *slogObserver) GiveUp(ctx context.Context, a Attempt, reason Reason, err error) {
+	o.log().LogAttrs(ctx, o.level, "retry gave up",
+		slog.Int("attempt", a.Number),
+		slog.Any("error", err),
+		slog.String("reason", reason.String()),
+		slog.Duration("elapsed", a.Latest().Sub(a.LoopStart)),
+	)
+}
@@ -0,0 +1,110 @@
+// SPDX-License-Identifier: MIT
+//
+// Copyright 2022 Andrew Bursavich. All rights reserved.
+// Use of this source code is governed by The MIT License
+// which can be found in the LICENSE file.
+
+package retry_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"slices"
+	"testing"
+	"time"
+
+	"bursavich.dev/retry"
+	"bursavich.dev/retry/retrytest"
+)
+
+func TestSlogElapsedUsesClock(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	clock := retrytest.NewAutoClock(time.Now())
+	r := retry.New(
+		retry.WithMaxRetries(retry.ConstantBackoff(time.Minute), 2),
+		retry.WithClock(clock),
+		retry.WithSlog(logger, slog.LevelInfo),
+	)
+	_ = r.Do(context.Background(), func() error { return errTest })
+
+	var elapsed []time.Duration
+	dec := json.NewDecoder(&buf)
+	for dec.More() {
+		var rec struct {
+			Elapsed time.Duration `json:"elapsed"`
+		}
+		if err := dec.Decode(&rec); err != nil {
+			t.Fatal(err)
+		}
+		elapsed = append(elapsed, rec.Elapsed)
+	}
+	if want := []time.Duration{0, time.Minute, 2 * time.Minute}; !slices.Equal(elapsed, want) {
+		t.Errorf("elapsed: got %v; want %v", elapsed, want)
+	}
+}
+
+func TestSlogDefaultLogger(t *testing.T) {
+	var buf bytes.Buffer
+	defer slog.SetDefault(slog.Default())
+	r := retry.New(
+		retry.WithMaxRetries(retry.ConstantBackoff(time.Minute), 1),
+		retry.WithClock(retrytest.NewAutoClock(time.Now())),
+		retry.WithSlog(nil, slog.LevelInfo),
+	)
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&buf, nil))) // after the option
+	_ = r.Do(context.Background(), func() error { return errTest })
+
+	var msgs []string
+	dec := json.NewDecoder(&buf)
+	for dec.More() {
+		var rec struct {
+			Msg string `json:"msg"`
+		}
+		if err := dec.Decode(&rec); err != nil {
+			t.Fatal(err)
+		}
+		msgs = append(msgs, rec.Msg)
+	}
+	if want := []string{"retrying", "retry gave up"}; !slices.Equal(msgs, want) {
+		t.Errorf("messages: got %q; want %q", msgs, want)
+	}
+}
+
+func TestAttemptLatest(t *testing.T) {
+	start := time.Now()
+	at := func(d time.Duration) time.Time { return start.Add(d) }
+	tests := []struct {
+		name    string
+		attempt retry.Attempt
+		want    time.Time
+	}{
+		{
+			name:    "loop start",
+			attempt: retry.Attempt{LoopStart: start},
+			want:    start,
+		},
+		{
+			name:    "started",
+			attempt: retry.Attempt{LoopStart: start, Woke: at(time.Second), Start: at(time.Second)},
+			want:    at(time.Second),
+		},
+		{
+			name:    "ended",
+			attempt: retry.Attempt{LoopStart: start, Start: at(time.Second), End: at(2 * time.Second)},
+			want:    at(2 * time.Second),
+		},
+		{
+			name:    "backing off",
+			attempt: retry.Attempt{LoopStart: start, Start: at(time.Second), End: at(2 * time.Second), BackoffStart: at(3 * time.Second)},
+			want:    at(3 * time.Second),
+		},
+	}
+	for _, tt := range tests {
+		if got := tt.attempt.Latest(); !got.Equal(tt.want) {
+			t.Errorf("%s: Latest: got %v; want %v", tt.name, got, tt.want)
+		}
+	}
+}
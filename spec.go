@@ -0,0 +1,347 @@
+// SPDX-License-Identifier: MIT
+//
+// Copyright 2022 Andrew Bursavich. All rights reserved.
+// Use of this source code is governed by The MIT License
+// which can be found in the LICENSE file.
+
+package retry
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SpecVersion is the version of the policy spec encoding produced by this package.
+const SpecVersion = 1
+
+// Spec is a language-neutral description of a Policy. It allows the same policy
+// to be shared between services written in different languages.
+//
+// Its JSON encoding looks like:
+//
+//	{
+//	  "version": 1,
+//	  "type": "exponential",
+//	  "params": {"min": 0.15, "max": 15, "factor": 1.5},
+//	  "wrappers": [
+//	    {"type": "random_jitter", "params": {"factor": 0.5}},
+//	    {"type": "max_retries", "params": {"limit": 10}}
+//	  ]
+//	}
+//
+// Durations are encoded as a number of seconds. Wrappers are applied in order,
+// so the first wrapper wraps the base policy and the last wrapper is outermost.
+// A fastest or slowest wrapper combines the policy that it wraps with the policy
+// described by its nested spec:
+//
+//	{
+//	  "version": 1,
+//	  "type": "constant",
+//	  "params": {"backoff": 1},
+//	  "wrappers": [
+//	    {"type": "slowest", "policy": {"version": 1, "type": "constant", "params": {"backoff": 5}}}
+//	  ]
+//	}
+//
+// The supported types and their params are:
+//
+//	Type                      Params                      Constructor
+//	never                                                 Never
+//	constant                  backoff                     ConstantBackoff
+//	exponential               min, max, factor            ExponentialBackoff
+//	linear                    start, step, max            LinearBackoff
+//	polynomial                base, exponent, max         PolynomialBackoff
+//	proportional              fraction, min, max          ProportionalBackoff
+//	forever                   min, max                    Forever
+//	random_jitter             factor                      WithRandomJitter
+//	bounded_jitter            factor, min, max            WithBoundedJitter
+//	full_jitter                                           WithFullJitter
+//	equal_jitter                                          WithEqualJitter
+//	max_retries               limit                       WithMaxRetries
+//	max_elapsed               limit                       WithMaxElapsedDuration
+//	max_cumulative_backoff    limit                       WithMaxCumulativeBackoff
+//	attempts_per_window       n, window                   WithAttemptsPerWindow
+//	limits                    max_retries, max_elapsed    WithLimits
+//	first_retry_within        limit                       WithFirstRetryWithin
+//	exact_first_backoff                                   WithExactFirstBackoff
+//	monotone                                              WithMonotoneBackoff
+//	deadline_truncation       margin                      WithDeadlineTruncation
+//	max_backoff               max                         WithMaxBackoff
+//	min_backoff               min                         WithMinBackoff
+//	scale                     factor                      WithScale
+//	fastest                   (policy)                    Fastest
+//	slowest                   (policy)                    Slowest
+//
+// Policies that can't be described include ScheduleBackoff, Sequence, Switch,
+// and custom policies.
+type Spec struct {
+	Version  int                `json:"version"`
+	Type     string             `json:"type"`
+	Params   map[string]float64 `json:"params,omitempty"`
+	Wrappers []Layer            `json:"wrappers,omitempty"`
+}
+
+// Layer is a description of a single layer of a Policy.
+type Layer struct {
+	Type   string             `json:"type"`
+	Params map[string]float64 `json:"params,omitempty"`
+	// Policy is the policy that's combined with the wrapped policy
+	// by a fastest or slowest layer.
+	Policy *Spec `json:"policy,omitempty"`
+
+	other Policy // combined policy that's described by Policy
+}
+
+// A specifier is a Policy that can describe itself with a Layer.
+type specifier interface {
+	// spec returns a description of the policy's layer and its parent, if any.
+	spec() (layer Layer, parent Policy)
+}
+
+// SpecOf returns the Spec of the policy. It returns an error if any of the
+// policy's layers can't be described, such as custom policies.
+func SpecOf(policy Policy) (Spec, error) {
+	var wrappers []Layer
+	for p := policy; ; {
+		s, ok := p.(specifier)
+		if !ok {
+			return Spec{}, fmt.Errorf("retry: policy %s can't be described by a spec", policyName(p))
+		}
+		layer, parent := s.spec()
+		if layer.other != nil {
+			other, err := SpecOf(layer.other)
+			if err != nil {
+				return Spec{}, err
+			}
+			layer.Policy, layer.other = &other, nil
+		}
+		if parent == nil {
+			for i, k := 0, len(wrappers)-1; i < k; i, k = i+1, k-1 {
+				wrappers[i], wrappers[k] = wrappers[k], wrappers[i]
+			}
+			return Spec{
+				Version:  SpecVersion,
+				Type:     layer.Type,
+				Params:   layer.Params,
+				Wrappers: wrappers,
+			}, nil
+		}
+		wrappers = append(wrappers, layer)
+		p = parent
+	}
+}
+
+// Policy returns the Policy described by the Spec. It returns an error if the
+// version isn't supported or if any type or param is unknown or missing.
+func (s Spec) Policy() (Policy, error) {
+	if s.Version != SpecVersion {
+		return nil, fmt.Errorf("retry: unsupported spec version: %d", s.Version)
+	}
+	newBase, ok := specBases[s.Type]
+	if !ok {
+		return nil, fmt.Errorf("retry: unknown spec base type: %q", s.Type)
+	}
+	params := specParams{typ: s.Type, m: s.Params}
+	p := newBase(&params)
+	if err := params.done(); err != nil {
+		return nil, err
+	}
+	for _, w := range s.Wrappers {
+		if combine, ok := specCombinators[w.Type]; ok {
+			if w.Policy == nil {
+				return nil, fmt.Errorf("retry: spec type %q is missing its policy", w.Type)
+			}
+			other, err := w.Policy.Policy()
+			if err != nil {
+				return nil, err
+			}
+			params := specParams{typ: w.Type, m: w.Params}
+			if err := params.done(); err != nil {
+				return nil, err
+			}
+			p = combine(p, other)
+			continue
+		}
+		newWrapper, ok := specWrappers[w.Type]
+		if !ok {
+			return nil, fmt.Errorf("retry: unknown spec wrapper type: %q", w.Type)
+		}
+		if w.Policy != nil {
+			return nil, fmt.Errorf("retry: spec type %q doesn't combine policies", w.Type)
+		}
+		params := specParams{typ: w.Type, m: w.Params}
+		p = newWrapper(p, &params)
+		if err := params.done(); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// MarshalPolicy returns the JSON encoding of the policy's Spec.
+func MarshalPolicy(policy Policy) ([]byte, error) {
+	s, err := SpecOf(policy)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(s)
+}
+
+// UnmarshalPolicy parses the JSON encoding of a Spec and returns the Policy it describes.
+// Unknown fields, types, and params are rejected.
+func UnmarshalPolicy(data []byte) (Policy, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	var s Spec
+	if err := dec.Decode(&s); err != nil {
+		return nil, fmt.Errorf("retry: invalid spec: %w", err)
+	}
+	if dec.More() {
+		return nil, errors.New("retry: invalid spec: unexpected data after spec")
+	}
+	return s.Policy()
+}
+
+var specBases = map[string]func(*specParams) Policy{
+	"never": func(*specParams) Policy {
+		return Never()
+	},
+	"constant": func(p *specParams) Policy {
+		return ConstantBackoff(p.duration("backoff"))
+	},
+	"exponential": func(p *specParams) Policy {
+		return ExponentialBackoff(p.duration("min"), p.duration("max"), p.float("factor"))
+	},
+	"linear": func(p *specParams) Policy {
+		return LinearBackoff(p.duration("start"), p.duration("step"), p.duration("max"))
+	},
+	"polynomial": func(p *specParams) Policy {
+		return PolynomialBackoff(p.duration("base"), p.float("exponent"), p.duration("max"))
+	},
+	"forever": func(p *specParams) Policy {
+		return Forever(p.duration("min"), p.duration("max"))
+	},
+	"proportional": func(p *specParams) Policy {
+		return ProportionalBackoff(p.float("fraction"), p.duration("min"), p.duration("max"))
+	},
+}
+
+var specWrappers = map[string]func(Policy, *specParams) Policy{
+	"full_jitter": func(parent Policy, p *specParams) Policy {
+		return WithFullJitter(parent)
+	},
+	"equal_jitter": func(parent Policy, p *specParams) Policy {
+		return WithEqualJitter(parent)
+	},
+	"bounded_jitter": func(parent Policy, p *specParams) Policy {
+		return WithBoundedJitter(parent, p.float("factor"), p.duration("min"), p.duration("max"))
+	},
+	"random_jitter": func(parent Policy, p *specParams) Policy {
+		return WithRandomJitter(parent, p.float("factor"))
+	},
+	"max_backoff": func(parent Policy, p *specParams) Policy {
+		return WithMaxBackoff(parent, p.duration("max"))
+	},
+	"min_backoff": func(parent Policy, p *specParams) Policy {
+		return WithMinBackoff(parent, p.duration("min"))
+	},
+	"scale": func(parent Policy, p *specParams) Policy {
+		return WithScale(parent, p.float("factor"))
+	},
+	"max_retries": func(parent Policy, p *specParams) Policy {
+		return WithMaxRetries(parent, p.int("limit"))
+	},
+	"max_elapsed": func(parent Policy, p *specParams) Policy {
+		return WithMaxElapsedDuration(parent, p.duration("limit"))
+	},
+	"attempts_per_window": func(parent Policy, p *specParams) Policy {
+		return WithAttemptsPerWindow(parent, p.int("n"), p.duration("window"))
+	},
+	"max_cumulative_backoff": func(parent Policy, p *specParams) Policy {
+		return WithMaxCumulativeBackoff(parent, p.duration("limit"))
+	},
+	"limits": func(parent Policy, p *specParams) Policy {
+		return WithLimits(parent, p.int("max_retries"), p.duration("max_elapsed"))
+	},
+	"first_retry_within": func(parent Policy, p *specParams) Policy {
+		return WithFirstRetryWithin(parent, p.duration("limit"))
+	},
+	"exact_first_backoff": func(parent Policy, p *specParams) Policy {
+		return WithExactFirstBackoff(parent)
+	},
+	"monotone": func(parent Policy, p *specParams) Policy {
+		return WithMonotoneBackoff(parent)
+	},
+	"deadline_truncation": func(parent Policy, p *specParams) Policy {
+		return WithDeadlineTruncation(parent, WithTruncationMargin(p.duration("margin")))
+	},
+}
+
+// specCombinators are the wrappers that combine the wrapped policy with the policy
+// described by their layer.
+var specCombinators = map[string]func(Policy, Policy) Policy{
+	"fastest": Fastest,
+	"slowest": Slowest,
+}
+
+// specParams reads params and records missing and invalid params.
+type specParams struct {
+	typ     string
+	m       map[string]float64
+	used    map[string]bool
+	missing []string
+	invalid []string
+}
+
+func (p *specParams) float(name string) float64 {
+	v, ok := p.m[name]
+	if !ok {
+		p.missing = append(p.missing, name)
+		return 0
+	}
+	if p.used == nil {
+		p.used = make(map[string]bool)
+	}
+	p.used[name] = true
+	return v
+}
+
+func (p *specParams) int(name string) int {
+	v := p.float(name)
+	if v != math.Trunc(v) || math.Abs(v) > math.MaxInt32 {
+		p.invalid = append(p.invalid, name)
+		return 0
+	}
+	return int(v)
+}
+
+func (p *specParams) duration(name string) time.Duration {
+	return time.Duration(math.Round(p.float(name) * float64(time.Second)))
+}
+
+// done returns an error if any params were missing, invalid, or unknown.
+func (p *specParams) done() error {
+	if len(p.missing) > 0 {
+		return fmt.Errorf("retry: spec type %q is missing params: %s", p.typ, strings.Join(p.missing, ", "))
+	}
+	if len(p.invalid) > 0 {
+		return fmt.Errorf("retry: spec type %q has non-integer params: %s", p.typ, strings.Join(p.invalid, ", "))
+	}
+	var unknown []string
+	for name := range p.m {
+		if !p.used[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) == 0 {
+		return nil
+	}
+	sort.Strings(unknown)
+	return fmt.Errorf("retry: spec type %q has unknown params: %s", p.typ, strings.Join(unknown, ", "))
+}
@@ -0,0 +1,162 @@
+// SPDX-License-Identifier: MIT
+//
+// Copyright 2022 Andrew Bursavich. All rights reserved.
+// Use of this source code is governed by The MIT License
+// which can be found in the LICENSE file.
+
+package retry_test
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"bursavich.dev/retry"
+)
+
+func TestSpecRoundTrip(t *testing.T) {
+	bases := []retry.Policy{
+		retry.Never(),
+		retry.ConstantBackoff(time.Second),
+		retry.ExponentialBackoff(100*time.Millisecond, 10*time.Second, 2),
+		retry.LinearBackoff(time.Second, 2*time.Second, time.Minute),
+		retry.PolynomialBackoff(time.Second, 2, time.Minute),
+		retry.ProportionalBackoff(0.1, time.Second, time.Minute),
+		retry.Forever(time.Second, time.Minute),
+	}
+	for _, base := range bases {
+		roundTrip(t, base)
+	}
+
+	p := retry.ExponentialBackoff(100*time.Millisecond, 10*time.Second, 2)
+	p = retry.WithRandomJitter(p, 0.5)
+	p = retry.WithBoundedJitter(p, 0.5, time.Second, time.Minute)
+	p = retry.WithFullJitter(p)
+	p = retry.WithEqualJitter(p)
+	p = retry.WithMaxBackoff(p, time.Minute)
+	p = retry.WithMinBackoff(p, time.Second)
+	p = retry.WithScale(p, 2)
+	p = retry.WithMaxRetries(p, 10)
+	p = retry.WithMaxElapsedDuration(p, time.Hour)
+	p = retry.WithMaxCumulativeBackoff(p, time.Hour)
+	p = retry.WithAttemptsPerWindow(p, 5, time.Minute)
+	p = retry.WithLimits(p, 10, time.Hour)
+	p = retry.WithFirstRetryWithin(p, time.Second)
+	p = retry.WithExactFirstBackoff(p)
+	p = retry.WithMonotoneBackoff(p)
+	p = retry.WithDeadlineTruncation(p, retry.WithTruncationMargin(time.Second))
+	roundTrip(t, p)
+
+	floor := retry.WithMaxRetries(retry.ConstantBackoff(5*time.Second), 3)
+	roundTrip(t, retry.Slowest(retry.ExponentialBackoff(time.Second, time.Minute, 2), floor))
+	roundTrip(t, retry.WithMaxRetries(retry.Fastest(retry.Forever(time.Second, time.Minute), floor), 10))
+}
+
+func roundTrip(t *testing.T, p retry.Policy) {
+	t.Helper()
+	data, err := retry.MarshalPolicy(p)
+	if err != nil {
+		t.Fatalf("MarshalPolicy(%v): %v", p, err)
+	}
+	q, err := retry.UnmarshalPolicy(data)
+	if err != nil {
+		t.Fatalf("UnmarshalPolicy(%s): %v", data, err)
+	}
+	again, err := retry.MarshalPolicy(q)
+	if err != nil {
+		t.Fatalf("MarshalPolicy(%v): %v", q, err)
+	}
+	if string(again) != string(data) {
+		t.Errorf("round trip: got %s; want %s", again, data)
+	}
+	if got, want := retry.Table(q, 20), retry.Table(p, 20); !slices.Equal(got, want) {
+		t.Errorf("round trip: got bounds %v; want %v", got, want)
+	}
+}
+
+func TestSpecErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		spec string
+		want string
+	}{
+		{
+			name: "version",
+			spec: `{"version": 2, "type": "never"}`,
+			want: "unsupported spec version",
+		},
+		{
+			name: "unknown base",
+			spec: `{"version": 1, "type": "unknown"}`,
+			want: `unknown spec base type: "unknown"`,
+		},
+		{
+			name: "unknown wrapper",
+			spec: `{"version": 1, "type": "never", "wrappers": [{"type": "unknown"}]}`,
+			want: `unknown spec wrapper type: "unknown"`,
+		},
+		{
+			name: "missing params",
+			spec: `{"version": 1, "type": "exponential", "params": {"min": 1}}`,
+			want: `spec type "exponential" is missing params: max, factor`,
+		},
+		{
+			name: "unknown params",
+			spec: `{"version": 1, "type": "constant", "params": {"backoff": 1, "jitter": 1, "extra": 2}}`,
+			want: `spec type "constant" has unknown params: extra, jitter`,
+		},
+		{
+			name: "non-integer params",
+			spec: `{"version": 1, "type": "never", "wrappers": [{"type": "max_retries", "params": {"limit": 2.5}}]}`,
+			want: `spec type "max_retries" has non-integer params: limit`,
+		},
+		{
+			name: "missing policy",
+			spec: `{"version": 1, "type": "never", "wrappers": [{"type": "fastest"}]}`,
+			want: `spec type "fastest" is missing its policy`,
+		},
+		{
+			name: "unexpected policy",
+			spec: `{"version": 1, "type": "never", "wrappers": [{"type": "monotone", "policy": {"version": 1, "type": "never"}}]}`,
+			want: `spec type "monotone" doesn't combine policies`,
+		},
+		{
+			name: "invalid policy",
+			spec: `{"version": 1, "type": "never", "wrappers": [{"type": "slowest", "policy": {"version": 1, "type": "unknown"}}]}`,
+			want: `unknown spec base type: "unknown"`,
+		},
+		{
+			name: "unknown fields",
+			spec: `{"version": 1, "type": "never", "extra": 1}`,
+			want: "invalid spec",
+		},
+		{
+			name: "trailing data",
+			spec: `{"version": 1, "type": "never"} {}`,
+			want: "unexpected data after spec",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := retry.UnmarshalPolicy([]byte(tt.spec))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error: got %v; want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSpecOfUnsupported(t *testing.T) {
+	policies := []retry.Policy{
+		retry.ScheduleBackoff([]time.Duration{time.Second}, retry.ExhaustStop),
+		retry.Sequence(retry.ConstantBackoff(time.Second), 2, retry.ConstantBackoff(time.Minute)),
+		retry.WithMaxRetries(retry.ScheduleBackoff(nil, retry.ExhaustStop), 1),
+		retry.Fastest(retry.ConstantBackoff(time.Second), retry.ScheduleBackoff(nil, retry.ExhaustStop)),
+	}
+	for _, p := range policies {
+		if _, err := retry.SpecOf(p); err == nil {
+			t.Errorf("SpecOf(%v): got nil error", p)
+		}
+	}
+}
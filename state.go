@@ -0,0 +1,135 @@
+// SPDX-License-Identifier: MIT
+//
+// Copyright 2022 Andrew Bursavich. All rights reserved.
+// Use of this source code is governed by The MIT License
+// which can be found in the LICENSE file.
+
+package retry
+
+import (
+	"maps"
+	"math/rand/v2"
+	"time"
+)
+
+// State is the state of a retry loop that's provided to a StatePolicy.
+type State struct {
+	// Err is the error returned by the latest attempt.
+	Err error
+	// Start is the time at which the retry loop started. It precedes the first
+	// attempt by the initial delay, if one is configured by WithInitialDelay.
+	Start time.Time
+	// Now is the time at which the latest attempt ended.
+	Now time.Time
+	// Attempt is the number of the next retry attempt, starting at 1.
+	// A StatePolicy may skip ahead with SkipTo.
+	Attempt int
+	// Deadline is the time by which the retry loop must finish,
+	// or the zero time if there isn't one.
+	//
+	// It's only provided if the Retrier was configured with WithPolicyDeadline.
+	Deadline time.Time
+
+	values map[any]any
+	steps  *[]Step
+	rand   *lockedRand
+	exact  bool  // jitter layers don't jitter the backoff
+	hit    Limit // the first Limit that stopped the latest decision
+}
+
+// SkipTo advances the retry loop to the given attempt if it's after the current one.
+// Subsequent layers and decisions observe the new attempt number, so a policy can
+// jump to a later part of the backoff curve instead of walking the whole curve,
+// such as after receiving a severe throttling error.
+func (s *State) SkipTo(attempt int) {
+	if attempt > s.Attempt {
+		s.Attempt = attempt
+	}
+}
+
+// Value returns the value associated with key for the lifetime of the retry loop,
+// or nil if there isn't one.
+func (s *State) Value(key any) any {
+	return s.values[key]
+}
+
+// SetValue associates value with key for the lifetime of the retry loop.
+// It allows policies that are shared by many loops to keep per-loop state.
+// A policy should use a key that's unique to it, such as a pointer to itself.
+func (s *State) SetValue(key, value any) {
+	if s.values == nil {
+		s.values = make(map[any]any)
+	}
+	s.values[key] = value
+}
+
+// A StatePolicy is a Policy that makes decisions based on the State of the retry loop.
+//
+// A retry loop prefers NextState over Next if it's available. Policies that wrap
+// a parent should implement StatePolicy and pass the State through to the parent.
+type StatePolicy interface {
+	Policy
+
+	// NextState returns the backoff duration to wait before the next attempt
+	// and a bool indicating if a retry should be attempted.
+	NextState(s *State) (backoff time.Duration, retry bool)
+}
+
+// next calls NextState if the policy is a StatePolicy or Next otherwise.
+func next(p Policy, s *State) (d time.Duration, ok bool) {
+	if sp, isState := p.(StatePolicy); isState {
+		d, ok = sp.NextState(s)
+	} else {
+		d, ok = p.Next(s.Err, s.Start, s.Now, s.Attempt)
+	}
+	s.trace(p, d, ok)
+	return d, ok
+}
+
+// fork returns a copy of the State whose changes, such as by SkipTo or SetValue,
+// don't affect s unless it's joined back into s.
+func (s *State) fork() *State {
+	f := *s
+	f.values = maps.Clone(s.values)
+	if s.steps != nil {
+		f.steps = new([]Step)
+	}
+	return &f
+}
+
+// join replaces s with the chosen one of its forks, appending the steps
+// recorded by each of the forks in order.
+func (s *State) join(chosen *State, forks ...*State) {
+	steps := s.steps
+	if steps != nil {
+		for _, f := range forks {
+			*steps = append(*steps, *f.steps...)
+		}
+	}
+	*s = *chosen
+	s.steps = steps
+}
+
+// stop records the Limit that stopped the latest decision, unless another
+// Limit, such as that of a parent, already stopped it.
+func (s *State) stop(l Limit) {
+	if s.hit == LimitNone {
+		s.hit = l
+	}
+}
+
+// limit returns the earlier of the deadline, if any, and the given time.
+func (s *State) limit(t time.Time) time.Time {
+	if !s.Deadline.IsZero() && s.Deadline.Before(t) {
+		return s.Deadline
+	}
+	return t
+}
+
+// float64 returns a pseudo-random number in [0, 1) from the loop's source of randomness.
+func (s *State) float64() float64 {
+	if s.rand != nil {
+		return s.rand.Float64()
+	}
+	return rand.Float64()
+}
@@ -0,0 +1,62 @@
+// SPDX-License-Identifier: MIT
+//
+// Copyright 2022 Andrew Bursavich. All rights reserved.
+// Use of this source code is governed by The MIT License
+// which can be found in the LICENSE file.
+
+package retry
+
+import "context"
+
+// Stream retries a resumable producer, such as a paginated export or a change stream,
+// according to the given policy and Retrier options and passes the values it emits to sink
+// without duplicates.
+//
+// Production is split into chunks that are identified by resume tokens. Each call to produce
+// starts at resumeToken, which is empty for the first chunk, emits the chunk's values, and
+// returns the token of the next chunk or an empty token if there are no more chunks.
+//
+// Emitted values are buffered until their chunk is produced successfully, so the values
+// emitted by a failed attempt are discarded and the chunk is retried from the same token.
+// Each chunk is retried independently, starting from the policy's first attempt.
+//
+// If sink returns an error, Stream stops and returns it without retrying.
+func Stream[T any](
+	ctx context.Context,
+	policy Policy,
+	produce func(ctx context.Context, resumeToken string, emit func(T) error) (string, error),
+	sink func(T) error,
+	opts ...Option,
+) error {
+	r := New(policy, opts...)
+	var (
+		token string
+		buf   []T
+	)
+	emit := func(v T) error {
+		buf = append(buf, v)
+		return nil
+	}
+	for {
+		var next string
+		err := r.Do(ctx, func() error {
+			clear(buf)
+			buf = buf[:0]
+			var err error
+			next, err = produce(ctx, token, emit)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		for _, v := range buf {
+			if err := sink(v); err != nil {
+				return err
+			}
+		}
+		if next == "" {
+			return nil
+		}
+		token = next
+	}
+}
@@ -0,0 +1,84 @@
+// SPDX-License-Identifier: MIT
+//
+// Copyright 2022 Andrew Bursavich. All rights reserved.
+// Use of this source code is governed by The MIT License
+// which can be found in the LICENSE file.
+
+package retry
+
+import (
+	"math"
+	"time"
+)
+
+// Table returns the min and max backoff bounds for each of the policy's first n retry attempts.
+// It's useful for capacity planning, such as computing the worst-case retry load of a policy.
+//
+// The bounds of the policies provided by this package, including their jitter, are computed
+// analytically. Other policies are sampled once per attempt with a nil error.
+//
+// Attempts are assumed to take no time and elapsed time is accumulated from the min backoffs,
+// so the table ends at the first attempt for which the policy can't possibly retry.
+// It returns nil if n isn't positive.
+func Table(policy Policy, n int) [][2]time.Duration {
+	if n <= 0 {
+		return nil
+	}
+	t := make([][2]time.Duration, 0, n)
+	var elapsed time.Duration
+	for attempt := 1; attempt <= n; attempt++ {
+		min, max, ok := bounds(policy, attempt, elapsed)
+		if !ok {
+			break
+		}
+		t = append(t, [2]time.Duration{min, max})
+		elapsed += min
+	}
+	return t
+}
+
+// A bounder is a Policy that can analytically compute the bounds of its backoffs.
+type bounder interface {
+	// bounds returns the min and max backoff for the attempt after the given elapsed duration
+	// and a bool indicating if a retry may be attempted.
+	bounds(attempt int, elapsed time.Duration) (min, max time.Duration, retry bool)
+}
+
+func bounds(p Policy, attempt int, elapsed time.Duration) (min, max time.Duration, retry bool) {
+	if b, ok := p.(bounder); ok {
+		return b.bounds(attempt, elapsed)
+	}
+	var start time.Time
+	d, ok := p.Next(nil, start, start.Add(elapsed), attempt)
+	return d, d, ok
+}
+
+// maxAmplificationAttempts is the number of retry attempts after which
+// a policy is considered to retry indefinitely.
+const maxAmplificationAttempts = 1000
+
+// Amplification returns the expected number of calls made per operation by the
+// policy if each call fails independently with the given probability.
+//
+// For example, if a policy allows 3 retry attempts and the failure rate is 0.5,
+// the expected amplification is 1 + 0.5 + 0.25 + 0.125 = 1.875.
+//
+// The number of retry attempts allowed by the policy is computed with Table,
+// so time-based limits assume that calls take no time.
+func Amplification(policy Policy, failureRate float64) float64 {
+	if failureRate <= 0 {
+		return 1
+	}
+	n := len(Table(policy, maxAmplificationAttempts))
+	if n == maxAmplificationAttempts {
+		if failureRate >= 1 {
+			return math.Inf(1)
+		}
+		return 1 / (1 - failureRate)
+	}
+	if failureRate >= 1 {
+		return float64(n + 1)
+	}
+	// Geometric series: sum of failureRate^k for k in [0, n].
+	return (1 - math.Pow(failureRate, float64(n+1))) / (1 - failureRate)
+}
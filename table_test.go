@@ -0,0 +1,32 @@
+// SPDX-License-Identifier: MIT
+//
+// Copyright 2022 Andrew Bursavich. All rights reserved.
+// Use of this source code is governed by The MIT License
+// which can be found in the LICENSE file.
+
+package retry_test
+
+import (
+	"testing"
+	"time"
+
+	"bursavich.dev/retry"
+)
+
+func TestTable(t *testing.T) {
+	policy := retry.WithMaxRetries(retry.ConstantBackoff(time.Second), 2)
+	tests := []struct {
+		n    int
+		want int
+	}{
+		{n: -1, want: 0},
+		{n: 0, want: 0},
+		{n: 1, want: 1},
+		{n: 5, want: 2},
+	}
+	for _, tt := range tests {
+		if got := retry.Table(policy, tt.n); len(got) != tt.want {
+			t.Errorf("Table(%d): got %d rows; want %d", tt.n, len(got), tt.want)
+		}
+	}
+}
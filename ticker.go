@@ -0,0 +1,162 @@
+// SPDX-License-Identifier: MIT
+//
+// Copyright 2022 Andrew Bursavich. All rights reserved.
+// Use of this source code is governed by The MIT License
+// which can be found in the LICENSE file.
+
+package retry
+
+import (
+	"sync"
+	"time"
+
+	"bursavich.dev/retry/internal/hooks"
+)
+
+// Ticker delivers ticks on a channel at the times scheduled by a Policy.
+// It's for components that manage their own select loops and can't be
+// structured around a callback, such as Do.
+//
+// A Ticker isn't safe for concurrent use.
+//
+//	t := retry.NewTicker(policy)
+//	defer t.Stop()
+//	for {
+//		if err := attempt(); err == nil {
+//			t.Reset()
+//		} else if _, ok := t.Fail(err); !ok {
+//			return err
+//		}
+//		select {
+//		case <-t.C:
+//		case msg := <-msgs:
+//			// ...
+//		}
+//	}
+type Ticker struct {
+	// C is the channel on which the ticks are delivered.
+	C <-chan time.Time
+
+	policy Policy
+	clock  Clock
+	state  State
+
+	mu    sync.Mutex
+	c     chan time.Time
+	timer Timer         // of the scheduled tick, if any
+	done  chan struct{} // closed when the scheduled tick is canceled
+}
+
+// A TickerOption configures a Ticker.
+type TickerOption interface {
+	apply(*Ticker)
+}
+
+type tickerOptionFunc func(*Ticker)
+
+func (fn tickerOptionFunc) apply(t *Ticker) { fn(t) }
+
+// WithTickerClock returns a TickerOption that sets the Clock used by the Ticker.
+func WithTickerClock(c Clock) TickerOption {
+	return tickerOptionFunc(func(t *Ticker) {
+		if c != nil {
+			t.clock = c
+		}
+	})
+}
+
+// NewTicker returns a new Ticker with the given policy.
+// It doesn't deliver a tick until an attempt fails.
+func NewTicker(policy Policy, opts ...TickerOption) *Ticker {
+	c := make(chan time.Time, 1)
+	t := &Ticker{
+		C:      c,
+		policy: policy,
+		clock:  systemClock{},
+		c:      c,
+	}
+	for _, o := range opts {
+		o.apply(t)
+	}
+	t.Reset()
+	return t
+}
+
+// Fail reports that an attempt failed with the given error and schedules a tick
+// after the policy's backoff. It returns the backoff and a bool indicating if
+// a retry should be attempted. If not, no tick is scheduled.
+func (t *Ticker) Fail(err error) (time.Duration, bool) {
+	now := t.clock.Now()
+	t.state.Err = err
+	t.state.Now = now
+	t.state.Attempt++
+	d, ok := next(t.policy, &t.state)
+	if !ok {
+		t.cancel()
+		return 0, false
+	}
+	if notBefore, ok := NotBefore(err); ok {
+		d = max(d, notBefore.Sub(now))
+	}
+	if _, ok := t.clock.(systemClock); ok && d > 0 {
+		hooks.Sleep(d)
+	}
+	t.schedule(d)
+	return d, true
+}
+
+// Reset resets the Ticker's attempts and elapsed time, such as after an attempt succeeds,
+// and stops any scheduled tick.
+func (t *Ticker) Reset() {
+	t.cancel()
+	t.state = State{Start: t.clock.Now()}
+}
+
+// Stop stops the Ticker. No more ticks will be delivered.
+func (t *Ticker) Stop() {
+	t.cancel()
+}
+
+// schedule cancels any scheduled tick and schedules a tick after d.
+func (t *Ticker) schedule(d time.Duration) {
+	t.cancel()
+	timer, done := t.clock.NewTimer(d), make(chan struct{})
+	t.mu.Lock()
+	t.timer, t.done = timer, done
+	t.mu.Unlock()
+	go func() {
+		var now time.Time
+		select {
+		case <-done:
+			return
+		case now = <-timer.C():
+		}
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		select {
+		case <-done:
+			return // Canceled while it fired.
+		default:
+		}
+		t.timer, t.done = nil, nil
+		select {
+		case t.c <- now:
+		default:
+		}
+	}()
+}
+
+// cancel cancels the scheduled tick, if any, and discards a tick that wasn't received.
+func (t *Ticker) cancel() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.timer != nil {
+		t.timer.Stop()
+		close(t.done)
+		t.timer, t.done = nil, nil
+	}
+	select {
+	case <-t.c:
+	default:
+	}
+}
@@ -0,0 +1,81 @@
+// SPDX-License-Identifier: MIT
+//
+// Copyright 2022 Andrew Bursavich. All rights reserved.
+// Use of this source code is governed by The MIT License
+// which can be found in the LICENSE file.
+
+package retry_test
+
+import (
+	"testing"
+	"time"
+
+	"bursavich.dev/retry"
+	"bursavich.dev/retry/retrytest"
+)
+
+func TestTicker(t *testing.T) {
+	retrytest.RequireNoSleep(t)
+	start := time.Now()
+	clock := retrytest.NewClock(start)
+	tk := retry.NewTicker(retry.WithMaxRetries(retry.ConstantBackoff(time.Second), 2), retry.WithTickerClock(clock))
+	defer tk.Stop()
+
+	// Nothing is scheduled until an attempt fails.
+	clock.Advance(time.Hour)
+	expectNoTick(t, tk)
+
+	if d, ok := tk.Fail(errTest); !ok || d != time.Second {
+		t.Fatalf("Fail: got (%v, %v); want (1s, true)", d, ok)
+	}
+	expectNoTick(t, tk)
+	clock.Advance(time.Second)
+	expectTick(t, tk)
+
+	// Backpressure delays the tick.
+	if d, ok := tk.Fail(retry.Backpressure(errTest, clock.Now().Add(5*time.Second))); !ok || d != 5*time.Second {
+		t.Fatalf("Fail: got (%v, %v); want (5s, true)", d, ok)
+	}
+	clock.Advance(time.Second)
+	expectNoTick(t, tk)
+	clock.Advance(4 * time.Second)
+	expectTick(t, tk)
+
+	// The policy stops.
+	if d, ok := tk.Fail(errTest); ok {
+		t.Fatalf("Fail: got (%v, %v); want (0, false)", d, ok)
+	}
+
+	// Reset starts over and cancels the scheduled tick.
+	tk.Reset()
+	if _, ok := tk.Fail(errTest); !ok {
+		t.Fatal("Fail after Reset: got false; want true")
+	}
+	tk.Reset()
+	clock.Advance(time.Hour)
+	expectNoTick(t, tk)
+
+	// Stop cancels the scheduled tick.
+	tk.Fail(errTest)
+	tk.Stop()
+	clock.Advance(time.Hour)
+	expectNoTick(t, tk)
+}
+
+func expectTick(t *testing.T, tk *retry.Ticker) {
+	t.Helper()
+	select {
+	case <-tk.C:
+	case <-time.After(time.Second):
+		t.Fatal("no tick was delivered")
+	}
+}
+
+func expectNoTick(t *testing.T, tk *retry.Ticker) {
+	t.Helper()
+	select {
+	case <-tk.C:
+		t.Fatal("unexpected tick")
+	case <-time.After(10 * time.Millisecond):
+	}
+}
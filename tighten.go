@@ -0,0 +1,73 @@
+// SPDX-License-Identifier: MIT
+//
+// Copyright 2022 Andrew Bursavich. All rights reserved.
+// Use of this source code is governed by The MIT License
+// which can be found in the LICENSE file.
+
+package retry
+
+import (
+	"context"
+	"time"
+)
+
+type tighteningKey struct{}
+
+// tightening caps the policies of retry loops.
+type tightening struct {
+	maxAttempts int
+	maxBackoff  time.Duration
+}
+
+// WithTightening returns a context that caps the policy of the retry loops that use it,
+// or a context derived from it, to at most maxAttempts attempts and a backoff of at most
+// maxBackoff. It lets latency-critical code bound the worst case of library code that it
+// doesn't control.
+//
+// It applies to the loops that take a context: those of a Retrier and of the functions
+// built on it, Attempts, Repeat and Watch, which cap the invocations since the latest
+// success, and Sender.Run, which caps the attempts of each item. It doesn't apply to a
+// Ticker, which doesn't take a context.
+//
+// A retry loop gives up if backpressure requires it to wait longer than maxBackoff.
+// Non-positive limits aren't enforced. If the context is already tightened, the
+// stricter of the limits are enforced.
+func WithTightening(ctx context.Context, maxAttempts int, maxBackoff time.Duration) context.Context {
+	t := tightening{maxAttempts: maxAttempts, maxBackoff: maxBackoff}
+	if p, ok := ctx.Value(tighteningKey{}).(tightening); ok {
+		t.maxAttempts = minLimit(t.maxAttempts, p.maxAttempts)
+		t.maxBackoff = minLimit(t.maxBackoff, p.maxBackoff)
+	}
+	return context.WithValue(ctx, tighteningKey{}, t)
+}
+
+// tighteningFrom returns the tightening of ctx.
+func tighteningFrom(ctx context.Context) tightening {
+	t, _ := ctx.Value(tighteningKey{}).(tightening)
+	return t
+}
+
+// allows reports whether a retry is allowed after the given number of attempts.
+func (t tightening) allows(attempts int) bool {
+	return t.maxAttempts <= 0 || attempts < t.maxAttempts
+}
+
+// cap returns the backoff capped to the max.
+func (t tightening) cap(d time.Duration) time.Duration {
+	if t.maxBackoff > 0 && d > t.maxBackoff {
+		return t.maxBackoff
+	}
+	return d
+}
+
+// minLimit returns the lesser of the limits, ignoring those that aren't positive.
+func minLimit[T int | time.Duration](a, b T) T {
+	switch {
+	case a <= 0:
+		return b
+	case b <= 0:
+		return a
+	default:
+		return min(a, b)
+	}
+}
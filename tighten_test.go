@@ -0,0 +1,116 @@
+// SPDX-License-Identifier: MIT
+//
+// Copyright 2022 Andrew Bursavich. All rights reserved.
+// Use of this source code is governed by The MIT License
+// which can be found in the LICENSE file.
+
+package retry_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"bursavich.dev/retry"
+	"bursavich.dev/retry/retrytest"
+)
+
+func TestTightening(t *testing.T) {
+	ctx := retry.WithTightening(context.Background(), 5, time.Second)
+	ctx = retry.WithTightening(ctx, 3, time.Minute) // the stricter limits apply
+	policy := retry.ConstantBackoff(time.Hour)
+
+	t.Run("Retrier", func(t *testing.T) {
+		now := time.Now()
+		clock := retrytest.NewAutoClock(now)
+		var starts []time.Duration
+		err := retry.New(policy, retry.WithClock(clock)).Do(ctx, func() error {
+			starts = append(starts, clock.Now().Sub(now))
+			return errTest
+		})
+		if !errors.Is(err, errTest) {
+			t.Errorf("Do: got %v; want %v", err, errTest)
+		}
+		if want := []time.Duration{0, time.Second, 2 * time.Second}; !slices.Equal(starts, want) {
+			t.Errorf("starts: got %v; want %v", starts, want)
+		}
+	})
+
+	t.Run("Attempts", func(t *testing.T) {
+		clock := retrytest.NewAutoClock(time.Now())
+		var attempts []int
+		for n, err := range retry.Attempts(ctx, policy, retry.WithClock(clock)) {
+			if err != nil {
+				if !errors.Is(err, retry.ErrExhausted) {
+					t.Errorf("Attempts: got %v; want %v", err, retry.ErrExhausted)
+				}
+				break
+			}
+			attempts = append(attempts, n)
+		}
+		if want := []int{1, 2, 3}; !slices.Equal(attempts, want) {
+			t.Errorf("attempts: got %v; want %v", attempts, want)
+		}
+	})
+
+	t.Run("Repeat", func(t *testing.T) {
+		clock := retrytest.NewAutoClock(time.Now())
+		calls := 0
+		err := retry.Repeat(ctx, policy, func() error {
+			if calls++; calls == 2 {
+				return nil // starts counting the attempts again
+			}
+			return errTest
+		}, retry.WithClock(clock))
+		if !errors.Is(err, errTest) {
+			t.Errorf("Repeat: got %v; want %v", err, errTest)
+		}
+		if want := 4; calls != want {
+			t.Errorf("calls: got %d; want %d", calls, want)
+		}
+	})
+
+	t.Run("Repeat backpressure", func(t *testing.T) {
+		clock := retrytest.NewAutoClock(time.Now())
+		calls := 0
+		err := retry.Repeat(ctx, policy, func() error {
+			calls++
+			return retry.Backpressure(errTest, clock.Now().Add(time.Hour))
+		}, retry.WithClock(clock))
+		if !errors.Is(err, errTest) {
+			t.Errorf("Repeat: got %v; want %v", err, errTest)
+		}
+		if calls != 1 {
+			t.Errorf("calls: got %d; want 1", calls)
+		}
+	})
+}
+
+func TestSenderTightening(t *testing.T) {
+	start := time.Now()
+	clock := retrytest.NewAutoClock(start)
+	ctx, cancel := context.WithCancel(retry.WithTightening(context.Background(), 2, time.Second))
+	defer cancel()
+
+	var sent []time.Duration
+	s := retry.NewSender(
+		retry.ConstantBackoff(time.Hour),
+		time.Second,
+		1,
+		func(ctx context.Context, item int) error {
+			sent = append(sent, clock.Now().Sub(start))
+			return errTest
+		},
+		retry.WithSenderClock[int](clock),
+		retry.WithDropHandler(func(ctx context.Context, item int, err error) {
+			cancel()
+		}),
+	)
+	s.Enqueue(1)
+	_ = s.Run(ctx)
+	if want := []time.Duration{time.Second, 2 * time.Second}; !slices.Equal(sent, want) {
+		t.Errorf("sent: got %v; want %v", sent, want)
+	}
+}
@@ -0,0 +1,81 @@
+// SPDX-License-Identifier: MIT
+//
+// Copyright 2022 Andrew Bursavich. All rights reserved.
+// Use of this source code is governed by The MIT License
+// which can be found in the LICENSE file.
+
+package retry
+
+import "fmt"
+
+// Validate returns warnings about known problematic compositions of the policy's layers,
+// such as jitter that's applied twice or an elapsed limit that's checked before jitter
+// can inflate the backoff beyond it.
+//
+// Only layers provided by this package are inspected.
+func Validate(policy Policy) []string {
+	var warnings []string
+	var walk func(p Policy, jitter Policy)
+	walk = func(p Policy, jitter Policy) {
+		if p == nil {
+			return
+		}
+		if _, ok := p.(jitterer); ok {
+			if jitter != nil {
+				warnings = append(warnings, fmt.Sprintf(
+					"%s is applied on top of %s: jitter is applied twice, so combine them into a single jitter layer",
+					policyName(jitter), policyName(p),
+				))
+			}
+			jitter = p
+		}
+		if _, ok := p.(elapsedLimiter); ok && jitter != nil {
+			warnings = append(warnings, fmt.Sprintf(
+				"%s is applied on top of %s: the elapsed limit is checked before jitter, so jittered backoffs may exceed it; apply the limit on top of the jitter instead",
+				policyName(jitter), policyName(p),
+			))
+		}
+		if w, ok := p.(wrapper); ok {
+			for _, parent := range w.parents() {
+				walk(parent, jitter)
+			}
+		}
+	}
+	walk(policy, nil)
+	return warnings
+}
+
+// A wrapper is a Policy that wraps parent policies.
+type wrapper interface {
+	parents() []Policy
+}
+
+// A jitterer is a Policy that adds random jitter to the backoff of its parents.
+type jitterer interface {
+	jitter()
+}
+
+// A deadlineUser is a Policy that requires the retry loop's deadline in its State.
+type deadlineUser interface {
+	usesDeadline()
+}
+
+// usesDeadline reports whether any of the policy's layers is a deadlineUser.
+func usesDeadline(p Policy) bool {
+	if _, ok := p.(deadlineUser); ok {
+		return true
+	}
+	if w, ok := p.(wrapper); ok {
+		for _, parent := range w.parents() {
+			if usesDeadline(parent) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// An elapsedLimiter is a Policy that limits the elapsed duration in which retries are allowed.
+type elapsedLimiter interface {
+	limitsElapsed()
+}
@@ -0,0 +1,104 @@
+// SPDX-License-Identifier: MIT
+//
+// Copyright 2022 Andrew Bursavich. All rights reserved.
+// Use of this source code is governed by The MIT License
+// which can be found in the LICENSE file.
+
+package retry
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// WarmStart executes retriable functions according to a Policy and remembers, per key,
+// the attempt at which the last recovery succeeded. Subsequent retry loops for the key
+// start their backoffs from one attempt before that, so dependencies with long recovery
+// times don't spend their early retries on futile attempts. Starting one attempt earlier
+// allows the remembered attempt to decay as recoveries get faster.
+//
+// Only the backoffs are offset: the policy decides whether to retry with the loop's
+// actual attempt number, so limits such as WithMaxRetries apply to each loop in full.
+// If the policy wouldn't retry the offset attempt, the latest offset backoff is reused.
+// If a loop doesn't succeed, the remembered attempt for its key is forgotten.
+//
+// It's safe for concurrent use.
+type WarmStart struct {
+	policy Policy
+	opts   []Option
+
+	mu     sync.Mutex
+	levels map[string]int
+}
+
+// NewWarmStart returns a new WarmStart with the given policy and Retrier options.
+func NewWarmStart(policy Policy, opts ...Option) *WarmStart {
+	return &WarmStart{
+		policy: policy,
+		opts:   opts,
+		levels: make(map[string]int),
+	}
+}
+
+// Do executes the retriable function for the key according to the WarmStart's policy.
+// It has the same semantics as Retrier.Do.
+func (w *WarmStart) Do(ctx context.Context, key string, fn func() error) error {
+	p := &warmStartPolicy{parent: w.policy, offset: max(w.level(key)-2, 0)}
+	if err := New(p, w.opts...).Do(ctx, fn); err != nil {
+		w.Reset(key)
+		return err
+	}
+	if p.last > 0 {
+		w.mu.Lock()
+		w.levels[key] = p.last
+		w.mu.Unlock()
+	}
+	return nil
+}
+
+// Reset forgets the attempt at which the last recovery for the key succeeded.
+func (w *WarmStart) Reset(key string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	delete(w.levels, key)
+}
+
+func (w *WarmStart) level(key string) int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.levels[key]
+}
+
+// warmStartPolicy is the Policy of a single WarmStart retry loop.
+type warmStartPolicy struct {
+	parent Policy
+	offset int           // of the attempts of the backoff curve
+	last   int           // attempt of the backoff curve of the latest decision
+	warm   time.Duration // latest backoff of the backoff curve
+}
+
+func (p *warmStartPolicy) Next(err error, start, now time.Time, attempt int) (time.Duration, bool) {
+	return p.NextState(&State{Err: err, Start: start, Now: now, Attempt: attempt})
+}
+
+func (p *warmStartPolicy) NextState(s *State) (time.Duration, bool) {
+	p.last = s.Attempt + p.offset
+	d, ok := next(p.parent, s)
+	if !ok || p.offset == 0 {
+		return d, ok
+	}
+	// The warm backoff is evaluated on a fork, so that it doesn't advance the loop.
+	warm := s.fork()
+	warm.Attempt += p.offset
+	if wd, wok := next(p.parent, warm); wok {
+		p.warm = wd
+	}
+	return max(d, p.warm), true
+}
+
+func (p *warmStartPolicy) String() string {
+	return "WarmStart"
+}
+
+func (p *warmStartPolicy) parents() []Policy { return []Policy{p.parent} }
@@ -0,0 +1,90 @@
+// SPDX-License-Identifier: MIT
+//
+// Copyright 2022 Andrew Bursavich. All rights reserved.
+// Use of this source code is governed by The MIT License
+// which can be found in the LICENSE file.
+
+package retry_test
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"bursavich.dev/retry"
+	"bursavich.dev/retry/retrytest"
+)
+
+func TestWarmStart(t *testing.T) {
+	start := time.Now()
+	clock := retrytest.NewAutoClock(start)
+	// The nth retry waits n seconds.
+	policy := retry.WithMaxRetries(retry.LinearBackoff(time.Second, time.Second, time.Hour), 5)
+	w := retry.NewWarmStart(policy, retry.WithClock(clock))
+
+	// run runs a loop for the key that succeeds on the given attempt, or never if it's 0,
+	// and returns the backoffs before its attempts.
+	run := func(key string, succeedAt int) []time.Duration {
+		var backoffs []time.Duration
+		prev := clock.Now()
+		_ = w.Do(context.Background(), key, func() error {
+			now := clock.Now()
+			backoffs = append(backoffs, now.Sub(prev))
+			prev = now
+			if len(backoffs) == succeedAt {
+				return nil
+			}
+			return errTest
+		})
+		return backoffs[1:]
+	}
+
+	steps := []struct {
+		name      string
+		key       string
+		succeedAt int
+		want      []time.Duration
+	}{
+		{
+			name:      "cold",
+			key:       "a",
+			succeedAt: 5,
+			want:      []time.Duration{1 * time.Second, 2 * time.Second, 3 * time.Second, 4 * time.Second},
+		},
+		{
+			name:      "warm",
+			key:       "a",
+			succeedAt: 3,
+			want:      []time.Duration{3 * time.Second, 4 * time.Second},
+		},
+		{
+			name:      "other key",
+			key:       "b",
+			succeedAt: 2,
+			want:      []time.Duration{1 * time.Second},
+		},
+		{
+			// The warm loop still gets all of its retries.
+			name: "warm give up",
+			key:  "a",
+			want: []time.Duration{3 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second, 5 * time.Second},
+		},
+		{
+			name:      "reset after give up",
+			key:       "a",
+			succeedAt: 2,
+			want:      []time.Duration{1 * time.Second},
+		},
+	}
+	for _, step := range steps {
+		if got := run(step.key, step.succeedAt); !slices.Equal(got, step.want) {
+			t.Errorf("%s: got backoffs %v; want %v", step.name, got, step.want)
+		}
+	}
+
+	w.Reset("b")
+	if got, want := run("b", 2), []time.Duration{time.Second}; !slices.Equal(got, want) {
+		t.Errorf("after Reset: got backoffs %v; want %v", got, want)
+	}
+}
@@ -0,0 +1,63 @@
+// SPDX-License-Identifier: MIT
+//
+// Copyright 2022 Andrew Bursavich. All rights reserved.
+// Use of this source code is governed by The MIT License
+// which can be found in the LICENSE file.
+
+package retry
+
+import "context"
+
+// Wrap returns a function that calls fn according to the given policy and Retrier options.
+//
+// It's useful for decorating implementations of interface methods when they're
+// constructed, rather than at every call site.
+func Wrap(policy Policy, fn func(context.Context) error, opts ...Option) func(context.Context) error {
+	r := New(policy, opts...)
+	return func(ctx context.Context) error {
+		return r.Do(ctx, func() error { return fn(ctx) })
+	}
+}
+
+// Wrap1 returns a function that calls fn according to the given policy and Retrier options.
+func Wrap1[A any](policy Policy, fn func(context.Context, A) error, opts ...Option) func(context.Context, A) error {
+	r := New(policy, opts...)
+	return func(ctx context.Context, a A) error {
+		return r.Do(ctx, func() error { return fn(ctx, a) })
+	}
+}
+
+// Wrap2 returns a function that calls fn according to the given policy and Retrier options.
+func Wrap2[A, B any](policy Policy, fn func(context.Context, A, B) error, opts ...Option) func(context.Context, A, B) error {
+	r := New(policy, opts...)
+	return func(ctx context.Context, a A, b B) error {
+		return r.Do(ctx, func() error { return fn(ctx, a, b) })
+	}
+}
+
+// WrapValue returns a function that calls fn according to the given policy and Retrier options
+// and returns the results.
+func WrapValue[R any](policy Policy, fn func(context.Context) (R, error), opts ...Option) func(context.Context) (R, error) {
+	r := New(policy, opts...)
+	return func(ctx context.Context) (R, error) {
+		return doValue(ctx, r, func() (R, error) { return fn(ctx) })
+	}
+}
+
+// WrapValue1 returns a function that calls fn according to the given policy and Retrier options
+// and returns the results.
+func WrapValue1[A, R any](policy Policy, fn func(context.Context, A) (R, error), opts ...Option) func(context.Context, A) (R, error) {
+	r := New(policy, opts...)
+	return func(ctx context.Context, a A) (R, error) {
+		return doValue(ctx, r, func() (R, error) { return fn(ctx, a) })
+	}
+}
+
+// WrapValue2 returns a function that calls fn according to the given policy and Retrier options
+// and returns the results.
+func WrapValue2[A, B, R any](policy Policy, fn func(context.Context, A, B) (R, error), opts ...Option) func(context.Context, A, B) (R, error) {
+	r := New(policy, opts...)
+	return func(ctx context.Context, a A, b B) (R, error) {
+		return doValue(ctx, r, func() (R, error) { return fn(ctx, a, b) })
+	}
+}